@@ -0,0 +1,335 @@
+package ploto
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/feiin/sqlstring"
+)
+
+// placeholder the bind parameter in the query
+type placeholder struct {
+	// start, end the byte offsets in the query
+	start, end int
+	// kind '?' for ?, ':' for :name, '@' for @name and @p1
+	kind byte
+	// name the name of :name and @name
+	name string
+}
+
+// isNameChar reports whether c can be a part of the parameter name
+func isNameChar(c byte) bool {
+	return c == '_' || c == '.' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// parsePlaceholders returns the placeholders in the query, skipping the string literals,
+// the quoted identifiers and the comments of the dialect. mysql, the default, escapes by backslash
+// in the literals and has `identifier` and # comment, mssql and sqlserver have [identifier].
+func parsePlaceholders(dialect string, query string) []placeholder {
+	var placeholders []placeholder
+	sqlServer := isSQLServer(dialect)
+
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`' && !sqlServer:
+			// 'string', "string", `identifier`, the doubled quote is read as two literals
+			for i++; i < len(query) && query[i] != c; i++ {
+				if query[i] == '\\' && c != '`' && !sqlServer {
+					i++
+				}
+			}
+		case c == '[' && sqlServer:
+			// [identifier], ]] is the escaped ]
+			for i++; i < len(query); i++ {
+				if query[i] == ']' {
+					if i+1 < len(query) && query[i+1] == ']' {
+						i++
+						continue
+					}
+					break
+				}
+			}
+		case c == '#' && !sqlServer:
+			// # comment
+			for i++; i < len(query) && query[i] != '\n'; i++ {
+			}
+		case c == '-' && i+1 < len(query) && query[i+1] == '-':
+			// -- comment
+			for i += 2; i < len(query) && query[i] != '\n'; i++ {
+			}
+		case c == '/' && i+1 < len(query) && query[i+1] == '*':
+			/* comment */
+			end := strings.Index(query[i+2:], "*/")
+			if end == -1 {
+				return placeholders
+			}
+			i += end + 3
+		case c == '?':
+			placeholders = append(placeholders, placeholder{start: i, end: i + 1, kind: '?'})
+		case c == ':' || c == '@':
+			// ::cast, :=, @@variable
+			if i+1 < len(query) && (query[i+1] == c || query[i+1] == '=') {
+				i++
+				continue
+			}
+			end := i + 1
+			for end < len(query) && isNameChar(query[end]) {
+				end++
+			}
+			name := strings.TrimRight(query[i+1:end], ".")
+			if name == "" {
+				continue
+			}
+			end = i + 1 + len(name)
+			placeholders = append(placeholders, placeholder{start: i, end: end, kind: c, name: name})
+			i = end - 1
+		}
+	}
+	return placeholders
+}
+
+// bindNamed compiles the :name and @name parameters of the query into the placeholders of the dialect,
+// ? for mysql and @p1, @p2 ... for mssql and sqlserver, the values are bound from arg, a map[string]interface{} or a struct with db tags
+func bindNamed(dialect string, query string, arg interface{}, mapper NameMapper) (string, []interface{}, error) {
+	placeholders := parsePlaceholders(dialect, query)
+
+	var b strings.Builder
+	b.Grow(len(query))
+	args := make([]interface{}, 0, len(placeholders))
+	last := 0
+
+	for _, p := range placeholders {
+		if p.kind == '?' {
+			return "", nil, fmt.Errorf("ploto: named query can not contain ? placeholder")
+		}
+
+		value, err := namedValue(arg, p.name, mapper)
+		if err != nil {
+			return "", nil, err
+		}
+		args = append(args, value)
+		b.WriteString(query[last:p.start])
+		writePlaceholder(&b, placeholderKind(dialect), len(args))
+		last = p.end
+	}
+	b.WriteString(query[last:])
+
+	return b.String(), args, nil
+}
+
+// namedValue returns the value of the named parameter
+func namedValue(arg interface{}, name string, mapper NameMapper) (interface{}, error) {
+	if m, ok := arg.(map[string]interface{}); ok {
+		value, ok := m[name]
+		if !ok {
+			return nil, fmt.Errorf("ploto: named parameter %s not found", name)
+		}
+		return value, nil
+	}
+
+	item := reflect.Indirect(reflect.ValueOf(arg))
+	if item.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("ploto: named arg must be a map[string]interface{} or a struct, got %T", arg)
+	}
+
+	info := getStructInfo(item.Type())
+	f, ok := info.tagged[name]
+	if !ok {
+		if mapper == nil {
+			mapper = DefaultNameMapper
+		}
+		f = mapperFieldNames(info, mapper)[mapper.ColumnName(name)]
+	}
+	if f == nil {
+		return nil, fmt.Errorf("ploto: named parameter %s not found in %s", name, item.Type())
+	}
+
+	return fieldArg(item, f)
+}
+
+// fieldArg returns the argument of the struct field, the json fields are marshaled
+func fieldArg(item reflect.Value, f *structField) (interface{}, error) {
+	value := fieldByIndexNoAlloc(item, f.index)
+	if !value.IsValid() {
+		// nil pointer struct along the path
+		return nil, nil
+	}
+	if f.json {
+		return jsonConverter{}.ToDB(value.Interface())
+	}
+	return value.Interface(), nil
+}
+
+// isExpandable reports whether the arg is a slice to be expanded into a list of placeholders,
+// []byte, driver.Valuer and the types with a registered converter are single values
+func isExpandable(converters *ConverterRegistry, arg interface{}) bool {
+	if arg == nil {
+		return false
+	}
+	if _, ok := arg.(driver.Valuer); ok {
+		return false
+	}
+	typ := reflect.TypeOf(arg)
+	if typ.Kind() != reflect.Slice || typ.Elem().Kind() == reflect.Uint8 {
+		return false
+	}
+	_, ok := lookupConverter(converters, typ)
+	return !ok
+}
+
+// parseOrdinal returns the N of the @pN placeholder of mssql and sqlserver, 0 for others,
+// @p1 is a user variable in mysql
+func parseOrdinal(dialect string, p placeholder) int {
+	if !isSQLServer(dialect) || p.kind != '@' || len(p.name) < 2 || p.name[0] != 'p' {
+		return 0
+	}
+	n, err := strconv.Atoi(p.name[1:])
+	if err != nil || n < 1 {
+		return 0
+	}
+	return n
+}
+
+// expandArgs expands the slice args into the placeholders of the query,
+// ? becomes ?,?,? and @pN of mssql and sqlserver is renumbered, the empty slice becomes NULL
+func expandArgs(dialect string, converters *ConverterRegistry, query string, args []interface{}) (string, []interface{}) {
+	expand := false
+	for _, arg := range args {
+		if isExpandable(converters, arg) {
+			expand = true
+			break
+		}
+	}
+	if !expand {
+		return query, args
+	}
+
+	// ordinals[i] the new ordinal of the first value of args[i]
+	ordinals := make([]int, len(args))
+	expanded := make([]interface{}, 0, len(args))
+	for i, arg := range args {
+		ordinals[i] = len(expanded) + 1
+		if !isExpandable(converters, arg) {
+			expanded = append(expanded, arg)
+			continue
+		}
+		value := reflect.ValueOf(arg)
+		for j := 0; j < value.Len(); j++ {
+			expanded = append(expanded, value.Index(j).Interface())
+		}
+	}
+
+	var b strings.Builder
+	b.Grow(len(query))
+	last, next := 0, 0
+
+	for _, p := range parsePlaceholders(dialect, query) {
+		var n int
+		switch {
+		case p.kind == '?':
+			next++
+			n = next
+		case parseOrdinal(dialect, p) > 0:
+			n = parseOrdinal(dialect, p)
+		default:
+			continue
+		}
+		if n > len(args) {
+			continue
+		}
+
+		b.WriteString(query[last:p.start])
+		last = p.end
+
+		if !isExpandable(converters, args[n-1]) {
+			writePlaceholder(&b, p.kind, ordinals[n-1])
+			continue
+		}
+
+		size := reflect.ValueOf(args[n-1]).Len()
+		if size == 0 {
+			b.WriteString("NULL")
+			continue
+		}
+		for j := 0; j < size; j++ {
+			if j > 0 {
+				b.WriteByte(',')
+			}
+			writePlaceholder(&b, p.kind, ordinals[n-1]+j)
+		}
+	}
+	b.WriteString(query[last:])
+
+	return b.String(), expanded
+}
+
+// writePlaceholder writes the ? or @pN placeholder
+func writePlaceholder(b *strings.Builder, kind byte, n int) {
+	if kind == '?' {
+		b.WriteByte('?')
+		return
+	}
+	b.WriteString("@p")
+	b.WriteString(strconv.Itoa(n))
+}
+
+// bindArgs rebinds the query if enabled, expands the slice args and converts the args with the registered converters
+func (db *DB) bindArgs(query string, args []interface{}) (string, []interface{}, error) {
+	if db.Rebind {
+		query = Rebind(db.Dialect, query)
+	}
+	query, args = expandArgs(db.Dialect, db.Converters, query, args)
+	args, err := convertArgs(db.Converters, args)
+	return query, args, err
+}
+
+// Rebind rebinds the ? placeholders of the query to the native style of the dialect,
+// @p1, @p2 ... for mssql and sqlserver, the ? in the string literals, [identifiers] and comments are kept
+func Rebind(dialect string, query string) string {
+	if !isSQLServer(dialect) {
+		return query
+	}
+
+	var b strings.Builder
+	b.Grow(len(query) + 8)
+	last, n := 0, 0
+	for _, p := range parsePlaceholders(dialect, query) {
+		if p.kind != '?' {
+			continue
+		}
+		n++
+		b.WriteString(query[last:p.start])
+		writePlaceholder(&b, '@', n)
+		last = p.end
+	}
+	b.WriteString(query[last:])
+	return b.String()
+}
+
+// formatQuery formats the query with the args for logging, ? and @pN of mssql and sqlserver are supported
+func formatQuery(dialect string, query string, args ...interface{}) string {
+	if len(args) == 0 || !isSQLServer(dialect) || !strings.Contains(query, "@") {
+		return sqlstring.Format(query, args...)
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, p := range parsePlaceholders(dialect, query) {
+		n := parseOrdinal(dialect, p)
+		if n == 0 || n > len(args) {
+			continue
+		}
+		b.WriteString(query[last:p.start])
+		b.WriteString(sqlstring.Escape(args[n-1]))
+		last = p.end
+	}
+	if last == 0 {
+		return sqlstring.Format(query, args...)
+	}
+	b.WriteString(query[last:])
+	return b.String()
+}
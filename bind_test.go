@@ -0,0 +1,262 @@
+package ploto
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestBindNamed(t *testing.T) {
+	type Profile struct {
+		Tags []string `db:"tags,json"`
+	}
+	type Manager struct {
+		Id int64 `db:"id"`
+	}
+	type Users struct {
+		Id      int64    `db:"id"`
+		Name    string   `db:"name"`
+		Email   string   // no db tag, bound by the NameMapper
+		Profile Profile  `db:"profile,json"`
+		Manager *Manager `db:"manager"`
+	}
+
+	user := Users{Id: 1, Name: "ploto", Email: "ploto@test.com", Profile: Profile{Tags: []string{"a"}}}
+
+	cases := []struct {
+		query string
+		arg   interface{}
+		sql   string
+		args  []interface{}
+	}{
+		{"select * from users where id=:id and name=@name", user, "select * from users where id=? and name=?", []interface{}{int64(1), "ploto"}},
+		{"select * from users where email=:email", &user, "select * from users where email=?", []interface{}{"ploto@test.com"}},
+		{"update users set profile=:profile where id=:id", user, "update users set profile=? where id=?", []interface{}{`{"Tags":["a"]}`, int64(1)}},
+		{"select * from users where manager_id=:manager.id", user, "select * from users where manager_id=?", []interface{}{nil}},
+		{"select ':id', \"@name\", `:x` -- :id\n from users /* @name */ where id=:id", user, "select ':id', \"@name\", `:x` -- :id\n from users /* @name */ where id=?", []interface{}{int64(1)}},
+		{"select @@version, '2021-01-01 10:00:00'::date where id=:id.", map[string]interface{}{"id": 2}, "select @@version, '2021-01-01 10:00:00'::date where id=?.", []interface{}{2}},
+	}
+
+	for _, c := range cases {
+		sql, args, err := bindNamed("mysql", c.query, c.arg, nil)
+		if err != nil {
+			t.Fatalf("bindNamed %s error %+v", c.query, err)
+		}
+		if sql != c.sql {
+			t.Fatalf("bindNamed %s sql: %s", c.query, sql)
+		}
+		if !reflect.DeepEqual(args, c.args) {
+			t.Fatalf("bindNamed %s args: %+v", c.query, args)
+		}
+	}
+
+	sql, args, err := bindNamed("sqlserver", "select * from users where id=:id and name=@name and [a:b]=:id", user, nil)
+	if err != nil || sql != "select * from users where id=@p1 and name=@p2 and [a:b]=@p3" || !reflect.DeepEqual(args, []interface{}{int64(1), "ploto", int64(1)}) {
+		t.Fatalf("bindNamed sqlserver %s %+v %+v", sql, args, err)
+	}
+
+	if _, _, err := bindNamed("mysql", "select * from users where id=:uid", user, nil); err == nil {
+		t.Fatalf("bindNamed missing field should be error")
+	}
+	if _, _, err := bindNamed("mysql", "select * from users where id=:uid", map[string]interface{}{}, nil); err == nil {
+		t.Fatalf("bindNamed missing key should be error")
+	}
+	if _, _, err := bindNamed("mysql", "select * from users where id=:id and name=?", user, nil); err == nil {
+		t.Fatalf("bindNamed with ? should be error")
+	}
+}
+
+func TestNamedQuery(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	db := &DB{DB: mockDB}
+
+	type Users struct {
+		Id   int64  `db:"id"`
+		Name string `db:"name"`
+	}
+
+	mock.ExpectQuery("select id,name from users where id=\\?").WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "ploto"))
+	var user Users
+	if err := db.NamedQueryRow("select id,name from users where id=:id", map[string]interface{}{"id": 1}).Scan(&user); err != nil {
+		t.Fatalf("NamedQueryRow error %+v", err)
+	}
+	if user.Id != 1 || user.Name != "ploto" {
+		t.Fatalf("NamedQueryRow user %+v", user)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("update users set name=\\? where id=\\?").WithArgs("feiin", 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Begin error %+v", err)
+	}
+	user.Name = "feiin"
+	if _, err := tx.NamedExec("update users set name=@name where id=@id", user); err != nil {
+		t.Fatalf("NamedExec error %+v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit error %+v", err)
+	}
+
+	mssql := &DB{DB: mockDB, Dialect: "sqlserver"}
+	mock.ExpectQuery("select id,name from users where name=@p1 and id in \\(@p2,@p3\\)").WithArgs("feiin", 1, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "feiin"))
+	if err := mssql.NamedQuery("select id,name from users where name=:name and id in (:ids)", map[string]interface{}{"name": "feiin", "ids": []int{1, 2}}).Scan(&user); err != nil {
+		t.Fatalf("NamedQuery sqlserver error %+v", err)
+	}
+
+	if err := db.NamedQuery("select * from users where id=:id", 1).Scan(&user); err == nil {
+		t.Fatalf("NamedQuery with int arg should be error")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestExpandArgs(t *testing.T) {
+	cases := []struct {
+		dialect string
+		query   string
+		args    []interface{}
+		sql     string
+		out     []interface{}
+	}{
+		{"mysql", "select * from users where id in (?)", []interface{}{[]int64{1, 2, 3}}, "select * from users where id in (?,?,?)", []interface{}{int64(1), int64(2), int64(3)}},
+		{"mysql", "select * from users where name=? and id in (?) and age>?", []interface{}{"ploto", []int{1, 2}, 18}, "select * from users where name=? and id in (?,?) and age>?", []interface{}{"ploto", 1, 2, 18}},
+		{"mysql", "select '?' from users where id in (?)", []interface{}{[]int{}}, "select '?' from users where id in (NULL)", []interface{}{}},
+		{"mysql", "select * from users where data=? and id in (?)", []interface{}{[]byte("x"), []string{"a"}}, "select * from users where data=? and id in (?)", []interface{}{[]byte("x"), "a"}},
+		{"mysql", "select @p1:=1, id from users where id in (?)", []interface{}{[]int{1, 2}}, "select @p1:=1, id from users where id in (?,?)", []interface{}{1, 2}},
+		{"sqlserver", "select * from users where id in (@p2) and name=@p1 and age>@p3", []interface{}{"ploto", []int{1, 2}, 18}, "select * from users where id in (@p2,@p3) and name=@p1 and age>@p4", []interface{}{"ploto", 1, 2, 18}},
+		{"sqlserver", "select * from users where id in (@p1) or parent_id in (@p1)", []interface{}{[]int{1, 2}}, "select * from users where id in (@p1,@p2) or parent_id in (@p1,@p2)", []interface{}{1, 2}},
+	}
+
+	for _, c := range cases {
+		sql, args := expandArgs(c.dialect, nil, c.query, c.args)
+		if sql != c.sql {
+			t.Fatalf("expandArgs %s sql: %s", c.query, sql)
+		}
+		if !reflect.DeepEqual(args, c.out) {
+			t.Fatalf("expandArgs %s args: %+v", c.query, args)
+		}
+	}
+
+	args := []interface{}{1}
+	if sql, out := expandArgs("mysql", nil, "select * from users where id=?", args); sql != "select * from users where id=?" || &out[0] != &args[0] {
+		t.Fatalf("expandArgs without slice should return the args")
+	}
+}
+
+func TestFormatQuery(t *testing.T) {
+	if sql := formatQuery("mysql", "select * from users where id in (?,?) and name=?", 1, 2, "ploto"); sql != "select * from users where id in (1,2) and name='ploto'" {
+		t.Fatalf("formatQuery ? sql: %s", sql)
+	}
+	if sql := formatQuery("sqlserver", "select '@p1' from users where id in (@p2,@p3) and name=@p1", "ploto", 1, 2); sql != "select '@p1' from users where id in (1,2) and name='ploto'" {
+		t.Fatalf("formatQuery @pN sql: %s", sql)
+	}
+	if sql := formatQuery("mysql", "select @p1 from users where id=?", 5); sql != "select @p1 from users where id=5" {
+		t.Fatalf("formatQuery mysql @var sql: %s", sql)
+	}
+}
+
+func TestQueryInSlice(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	db := &DB{DB: mockDB}
+
+	mock.ExpectQuery("select id from users where id in \\(\\?,\\?,\\?\\)").WithArgs(1, 2, 3).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+	var ids []int64
+	if err := db.Query("select id from users where id in (?)", []int64{1, 2, 3}).Scan(&ids); err != nil {
+		t.Fatalf("Query in slice error %+v", err)
+	}
+	if len(ids) != 2 {
+		t.Fatalf("Query in slice ids %+v", ids)
+	}
+
+	mock.ExpectExec("delete from users where id in \\(\\?,\\?\\)").WithArgs(1, 2).WillReturnResult(sqlmock.NewResult(0, 2))
+	if _, err := db.NamedExec("delete from users where id in (:ids)", map[string]interface{}{"ids": []int{1, 2}}); err != nil {
+		t.Fatalf("NamedExec in slice error %+v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestRebind(t *testing.T) {
+	query := "select '?', `?` from users /* ? */ where id=? and name=? -- ?"
+	if sql := Rebind("mysql", query); sql != query {
+		t.Fatalf("Rebind mysql sql: %s", sql)
+	}
+
+	cases := []struct {
+		dialect string
+		query   string
+		sql     string
+	}{
+		{"sqlserver", "select '?', \"?\" from users /* ? */ where id=? and name=? -- ?", "select '?', \"?\" from users /* ? */ where id=@p1 and name=@p2 -- ?"},
+		{"mssql", "select * from users where name='it''s ?' and id=?", "select * from users where name='it''s ?' and id=@p1"},
+		// no backslash escape, [identifier] and ]] escape on sqlserver
+		{"sqlserver", "select * from files where path='C:\\' and id=? and [a?]=? and [b]]?]=?", "select * from files where path='C:\\' and id=@p1 and [a?]=@p2 and [b]]?]=@p3"},
+		{"sqlserver", "select * from #tmp where id=?", "select * from #tmp where id=@p1"},
+	}
+	for _, c := range cases {
+		if sql := Rebind(c.dialect, c.query); sql != c.sql {
+			t.Fatalf("Rebind %s sql: %s", c.dialect, sql)
+		}
+	}
+}
+
+func TestParsePlaceholders(t *testing.T) {
+	cases := []struct {
+		dialect string
+		query   string
+		count   int
+	}{
+		{"mysql", "select * from users where name='it\\'s ?' and id=?", 1},
+		{"mysql", "select * from users # where id=?\n where name=?", 1},
+		{"mysql", "select `a?` from users where id=?", 1},
+		{"sqlserver", "select * from users where path='C:\\' and id=?", 1},
+		{"sqlserver", "select [a?] from users where id=?", 1},
+		{"sqlserver", "select * from #users where id=?", 1},
+	}
+	for _, c := range cases {
+		if placeholders := parsePlaceholders(c.dialect, c.query); len(placeholders) != c.count {
+			t.Fatalf("parsePlaceholders %s %s: %+v", c.dialect, c.query, placeholders)
+		}
+	}
+}
+
+func TestQueryRebind(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	db := &DB{DB: mockDB, Dialect: "sqlserver", Rebind: true}
+
+	mock.ExpectQuery("select id from users where id in \\(@p1,@p2\\) and status=@p3").WithArgs(1, 2, 1).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	var ids []int
+	if err := db.Query("select id from users where id in (?) and status=?", []int{1, 2}, 1).Scan(&ids); err != nil {
+		t.Fatalf("Query rebind error %+v", err)
+	}
+
+	mock.ExpectExec("update users set name=@p1 where id=@p2").WithArgs("ploto", 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	if _, err := db.NamedExec("update users set name=:name where id=:id", map[string]interface{}{"id": 1, "name": "ploto"}); err != nil {
+		t.Fatalf("NamedExec rebind error %+v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,162 @@
+package ploto
+
+import (
+	"context"
+	"strconv"
+	"strings"
+)
+
+// SelectBuilder builds the SELECT statement, e.g.
+//
+//	ploto.Select("id", "name").From("users").Where("status=?", 1).OrderBy("id desc").Limit(10)
+//
+// The columns, the table and the conditions are written as is, the conditions use ? placeholders.
+// Every method returns a new builder, so a shared base query can be extended safely.
+type SelectBuilder struct {
+	columns []string
+	table   string
+	where   []string
+	args    []interface{}
+	orderBy []string
+	limit   int
+	offset  int
+}
+
+// Select starts the SELECT statement of the columns, * if empty
+func Select(columns ...string) *SelectBuilder {
+	return &SelectBuilder{columns: append([]string(nil), columns...), limit: -1}
+}
+
+// clone returns the copy of the builder, the slices are full so that appending copies them
+func (q *SelectBuilder) clone() *SelectBuilder {
+	c := *q
+	c.where = c.where[:len(c.where):len(c.where)]
+	c.args = c.args[:len(c.args):len(c.args)]
+	c.orderBy = c.orderBy[:len(c.orderBy):len(c.orderBy)]
+	return &c
+}
+
+// From sets the table of the statement
+func (q *SelectBuilder) From(table string) *SelectBuilder {
+	c := q.clone()
+	c.table = table
+	return c
+}
+
+// Where adds the condition with ? placeholders, the conditions are joined by AND
+func (q *SelectBuilder) Where(cond string, args ...interface{}) *SelectBuilder {
+	c := q.clone()
+	c.where = append(c.where, cond)
+	c.args = append(c.args, args...)
+	return c
+}
+
+// OrderBy adds the ORDER BY expressions, e.g. "id desc"
+func (q *SelectBuilder) OrderBy(orderBy ...string) *SelectBuilder {
+	c := q.clone()
+	c.orderBy = append(c.orderBy, orderBy...)
+	return c
+}
+
+// Limit sets the max rows of the statement
+func (q *SelectBuilder) Limit(limit int) *SelectBuilder {
+	c := q.clone()
+	c.limit = limit
+	return c
+}
+
+// Offset sets the rows skipped by the statement
+func (q *SelectBuilder) Offset(offset int) *SelectBuilder {
+	c := q.clone()
+	c.offset = offset
+	return c
+}
+
+// ToSQL renders the statement and the args for the dialect, LIMIT ... OFFSET on mysql
+// and OFFSET ... FETCH with @p1 placeholders on mssql and sqlserver
+func (q *SelectBuilder) ToSQL(dialect string) (string, []interface{}) {
+	var b strings.Builder
+	b.WriteString("SELECT ")
+	if len(q.columns) == 0 {
+		b.WriteByte('*')
+	} else {
+		b.WriteString(strings.Join(q.columns, ","))
+	}
+
+	if q.table != "" {
+		b.WriteString(" FROM ")
+		b.WriteString(q.table)
+	}
+
+	where := q.where
+	paging := q.limit >= 0 || q.offset > 0
+	if q.limit == 0 && isSQLServer(dialect) {
+		// FETCH NEXT 0 ROWS is rejected by sqlserver, no rows by the condition instead
+		where = append(where[:len(where):len(where)], "1=0")
+		paging = false
+	}
+
+	if len(where) > 0 {
+		b.WriteString(" WHERE ")
+		for i, cond := range where {
+			if i > 0 {
+				b.WriteString(" AND ")
+			}
+			if len(where) > 1 {
+				cond = "(" + cond + ")"
+			}
+			b.WriteString(cond)
+		}
+	}
+
+	orderBy := q.orderBy
+	if len(orderBy) == 0 && paging && isSQLServer(dialect) {
+		// OFFSET ... FETCH requires ORDER BY
+		orderBy = []string{"(SELECT NULL)"}
+	}
+	if len(orderBy) > 0 {
+		b.WriteString(" ORDER BY ")
+		b.WriteString(strings.Join(orderBy, ","))
+	}
+
+	if paging {
+		if isSQLServer(dialect) {
+			b.WriteString(" OFFSET " + strconv.Itoa(q.offset) + " ROWS")
+			if q.limit >= 0 {
+				b.WriteString(" FETCH NEXT " + strconv.Itoa(q.limit) + " ROWS ONLY")
+			}
+		} else {
+			if q.limit >= 0 {
+				b.WriteString(" LIMIT " + strconv.Itoa(q.limit))
+			} else {
+				// the max rows of mysql, LIMIT is required by OFFSET
+				b.WriteString(" LIMIT 18446744073709551615")
+			}
+			if q.offset > 0 {
+				b.WriteString(" OFFSET " + strconv.Itoa(q.offset))
+			}
+		}
+	}
+
+	return Rebind(dialect, b.String()), append([]interface{}(nil), q.args...)
+}
+
+// queryDialect returns the dialect of the *DB or *Tx
+func queryDialect(queryer Queryer) string {
+	if sess, ok := queryer.(session); ok {
+		return sess.client().Dialect
+	}
+	return ""
+}
+
+// Query executes the statement on the *DB or *Tx, rendered for its dialect
+func (q *SelectBuilder) Query(ctx context.Context, queryer Queryer) *RowsResult {
+	query, args := q.ToSQL(queryDialect(queryer))
+	return queryer.QueryContext(ctx, query, args...)
+}
+
+// QueryRow executes the statement that is expected to return at most one row on the *DB or *Tx
+func (q *SelectBuilder) QueryRow(ctx context.Context, queryer Queryer) *RowResult {
+	query, args := q.ToSQL(queryDialect(queryer))
+	return queryer.QueryRowContext(ctx, query, args...)
+}
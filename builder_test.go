@@ -0,0 +1,109 @@
+package ploto
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestSelectBuilder(t *testing.T) {
+	cases := []struct {
+		query   *SelectBuilder
+		dialect string
+		sql     string
+		args    []interface{}
+	}{
+		{Select().From("users"), "mysql", "SELECT * FROM users", nil},
+		{Select("id", "name").From("users").Where("status=?", 1).Where("name like ? or email like ?", "a%", "a%").OrderBy("id desc").Limit(10).Offset(20),
+			"mysql", "SELECT id,name FROM users WHERE (status=?) AND (name like ? or email like ?) ORDER BY id desc LIMIT 10 OFFSET 20", []interface{}{1, "a%", "a%"}},
+		{Select("id").From("users").Offset(5), "mysql", "SELECT id FROM users LIMIT 18446744073709551615 OFFSET 5", nil},
+		{Select("id", "name").From("users").Where("status=?", 1).OrderBy("id desc").Limit(10).Offset(20),
+			"sqlserver", "SELECT id,name FROM users WHERE status=@p1 ORDER BY id desc OFFSET 20 ROWS FETCH NEXT 10 ROWS ONLY", []interface{}{1}},
+		{Select("id").From("users").Where("id in (?)", []int{1, 2}).Limit(1), "mssql", "SELECT id FROM users WHERE id in (@p1) ORDER BY (SELECT NULL) OFFSET 0 ROWS FETCH NEXT 1 ROWS ONLY", []interface{}{[]int{1, 2}}},
+		{Select("id").From("users").Offset(5), "sqlserver", "SELECT id FROM users ORDER BY (SELECT NULL) OFFSET 5 ROWS", nil},
+		{Select("id").From("users").Where("status=?", 1).OrderBy("id").Limit(0).Offset(5), "sqlserver", "SELECT id FROM users WHERE (status=@p1) AND (1=0) ORDER BY id", []interface{}{1}},
+		{Select("id").From("users").Limit(0), "mysql", "SELECT id FROM users LIMIT 0", nil},
+	}
+
+	for _, c := range cases {
+		sql, args := c.query.ToSQL(c.dialect)
+		if sql != c.sql {
+			t.Fatalf("ToSQL %s sql: %s", c.dialect, sql)
+		}
+		if !reflect.DeepEqual(args, c.args) {
+			t.Fatalf("ToSQL %s args: %+v", c.dialect, args)
+		}
+	}
+}
+
+func TestSelectBuilderQuery(t *testing.T) {
+	mockDB, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	db := &DB{DB: mockDB, Dialect: "sqlserver"}
+	ctx := context.Background()
+
+	type Users struct {
+		Id   int64  `db:"id"`
+		Name string `db:"name"`
+	}
+
+	mock.ExpectQuery("SELECT id,name FROM users WHERE id in (@p1,@p2) ORDER BY id OFFSET 0 ROWS FETCH NEXT 10 ROWS ONLY").WithArgs(1, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "a").AddRow(2, "b"))
+	var users []Users
+	if err := Select("id", "name").From("users").Where("id in (?)", []int{1, 2}).OrderBy("id").Limit(10).Query(ctx, db).Scan(&users); err != nil {
+		t.Fatalf("Select Query error %+v", err)
+	}
+	if len(users) != 2 || users[1].Name != "b" {
+		t.Fatalf("Select Query users %+v", users)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id,name FROM users WHERE id=@p1").WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "a"))
+	mock.ExpectCommit()
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Begin error %+v", err)
+	}
+	var user Users
+	if err := Select("id", "name").From("users").Where("id=?", 1).QueryRow(ctx, tx).Scan(&user); err != nil {
+		t.Fatalf("Select QueryRow error %+v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit error %+v", err)
+	}
+	if user.Name != "a" {
+		t.Fatalf("Select QueryRow user %+v", user)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestSelectBuilderCompose(t *testing.T) {
+	base := Select("id").From("users").Where("a=?", 1)
+	withB := base.Where("b=?", 2)
+	withC := base.Where("c=?", 3).OrderBy("id").Limit(10)
+
+	cases := []struct {
+		query *SelectBuilder
+		sql   string
+		args  []interface{}
+	}{
+		{base, "SELECT id FROM users WHERE a=?", []interface{}{1}},
+		{withB, "SELECT id FROM users WHERE (a=?) AND (b=?)", []interface{}{1, 2}},
+		{withC, "SELECT id FROM users WHERE (a=?) AND (c=?) ORDER BY id LIMIT 10", []interface{}{1, 3}},
+		{withB.OrderBy("name"), "SELECT id FROM users WHERE (a=?) AND (b=?) ORDER BY name", []interface{}{1, 2}},
+	}
+	for _, c := range cases {
+		sql, args := c.query.ToSQL("mysql")
+		if sql != c.sql || !reflect.DeepEqual(args, c.args) {
+			t.Fatalf("ToSQL sql: %s args: %+v", sql, args)
+		}
+	}
+}
@@ -0,0 +1,244 @@
+package ploto
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// coerceTimeLayouts the layouts of the datetime strings, tried in order
+var coerceTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+// isCoercible reports whether the values of typ can be coerced from the mismatched column types
+func isCoercible(typ reflect.Type) bool {
+	typ = indirectType(typ)
+	if reflect.PtrTo(typ).Implements(scannerType) {
+		return false
+	}
+	if typ == timeType {
+		return true
+	}
+	switch typ.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// coerceConverter converts the common representations into the target type, e.g. numeric strings,
+// RFC3339/MySQL datetime strings, 0/1 to bool and Unix timestamps to time.Time
+type coerceConverter struct{}
+
+// FromDB coerces src into dest
+func (coerceConverter) FromDB(src interface{}, dest interface{}) error {
+	return coerceValue(reflect.ValueOf(dest).Elem(), src)
+}
+
+// ToDB returns v
+func (coerceConverter) ToDB(v interface{}) (driver.Value, error) {
+	return v, nil
+}
+
+// coerceValue sets the driver value src to dest
+func coerceValue(dest reflect.Value, src interface{}) error {
+	if dest.Kind() == reflect.Ptr {
+		if src == nil {
+			dest.Set(reflect.Zero(dest.Type()))
+			return nil
+		}
+		value := reflect.New(dest.Type().Elem())
+		if err := coerceValue(value.Elem(), src); err != nil {
+			return err
+		}
+		dest.Set(value)
+		return nil
+	}
+
+	if src == nil {
+		return fmt.Errorf("converting NULL to %s is unsupported", dest.Type())
+	}
+	if b, ok := src.([]byte); ok {
+		src = string(b)
+		// BIT(1)
+		if len(b) == 1 && b[0] <= 1 && dest.Kind() == reflect.Bool {
+			src = int64(b[0])
+		}
+	}
+
+	if dest.Type() == timeType {
+		t, err := coerceTime(src)
+		if err != nil {
+			return err
+		}
+		dest.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	switch dest.Kind() {
+	case reflect.String:
+		switch v := src.(type) {
+		case string:
+			dest.SetString(v)
+		case int64:
+			dest.SetString(strconv.FormatInt(v, 10))
+		case float64:
+			dest.SetString(strconv.FormatFloat(v, 'f', -1, 64))
+		case bool:
+			dest.SetString(strconv.FormatBool(v))
+		case time.Time:
+			dest.SetString(v.Format(time.RFC3339Nano))
+		default:
+			return fmt.Errorf("converting %T to %s is unsupported", src, dest.Type())
+		}
+		return nil
+	case reflect.Bool:
+		switch v := src.(type) {
+		case bool:
+			dest.SetBool(v)
+		case int64:
+			dest.SetBool(v != 0)
+		case float64:
+			dest.SetBool(v != 0)
+		case string:
+			b, err := strconv.ParseBool(strings.TrimSpace(v))
+			if err != nil {
+				return fmt.Errorf("converting %q to %s: %v", v, dest.Type(), err)
+			}
+			dest.SetBool(b)
+		default:
+			return fmt.Errorf("converting %T to %s is unsupported", src, dest.Type())
+		}
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := coerceInt(src)
+		if err != nil {
+			return fmt.Errorf("converting %v to %s: %v", src, dest.Type(), err)
+		}
+		if dest.OverflowInt(i) {
+			return fmt.Errorf("converting %v to %s: value out of range", src, dest.Type())
+		}
+		dest.SetInt(i)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		i, err := coerceInt(src)
+		if err != nil {
+			return fmt.Errorf("converting %v to %s: %v", src, dest.Type(), err)
+		}
+		if i < 0 || dest.OverflowUint(uint64(i)) {
+			return fmt.Errorf("converting %v to %s: value out of range", src, dest.Type())
+		}
+		dest.SetUint(uint64(i))
+		return nil
+	case reflect.Float32, reflect.Float64:
+		f, err := coerceFloat(src)
+		if err != nil {
+			return fmt.Errorf("converting %v to %s: %v", src, dest.Type(), err)
+		}
+		if dest.OverflowFloat(f) {
+			return fmt.Errorf("converting %v to %s: value out of range", src, dest.Type())
+		}
+		dest.SetFloat(f)
+		return nil
+	}
+
+	return fmt.Errorf("converting %T to %s is unsupported", src, dest.Type())
+}
+
+// coerceInt converts the integer, integral float, bool or numeric string to int64
+func coerceInt(src interface{}) (int64, error) {
+	switch v := src.(type) {
+	case int64:
+		return v, nil
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	case float64:
+		if v != math.Trunc(v) {
+			return 0, fmt.Errorf("%v is not an integer", v)
+		}
+		return int64(v), nil
+	case string:
+		s := strings.TrimSpace(v)
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return i, nil
+		}
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return 0, err
+		}
+		return coerceInt(f)
+	case time.Time:
+		return v.Unix(), nil
+	}
+	return 0, fmt.Errorf("unsupported type %T", src)
+}
+
+// coerceFloat converts the number, bool or numeric string to float64
+func coerceFloat(src interface{}) (float64, error) {
+	switch v := src.(type) {
+	case float64:
+		return v, nil
+	case int64:
+		return float64(v), nil
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	case string:
+		return strconv.ParseFloat(strings.TrimSpace(v), 64)
+	}
+	return 0, fmt.Errorf("unsupported type %T", src)
+}
+
+// coerceTime converts the datetime string or Unix timestamp to time.Time,
+// the timestamps greater than 1e12 are in milliseconds
+func coerceTime(src interface{}) (time.Time, error) {
+	switch v := src.(type) {
+	case time.Time:
+		return v, nil
+	case int64:
+		return unixTime(float64(v)), nil
+	case float64:
+		return unixTime(v), nil
+	case string:
+		s := strings.TrimSpace(v)
+		if s == "" || strings.HasPrefix(s, "0000-00-00") {
+			return time.Time{}, nil
+		}
+		for _, layout := range coerceTimeLayouts {
+			if t, err := time.Parse(layout, s); err == nil {
+				return t, nil
+			}
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return unixTime(f), nil
+		}
+		return time.Time{}, fmt.Errorf("converting %q to time.Time: unknown datetime format", v)
+	}
+	return time.Time{}, fmt.Errorf("converting %T to time.Time is unsupported", src)
+}
+
+// unixTime returns the time of the Unix timestamp in seconds or milliseconds
+func unixTime(ts float64) time.Time {
+	if math.Abs(ts) >= 1e12 {
+		ts /= 1000
+	}
+	sec, frac := math.Modf(ts)
+	return time.Unix(int64(sec), int64(frac*1e9))
+}
@@ -0,0 +1,60 @@
+package ploto
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestCoerceTypes(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+
+	type Orders struct {
+		Id        int64      `db:"id"`
+		Amount    float64    `db:"amount"`
+		Paid      bool       `db:"paid"`
+		CreatedAt time.Time  `db:"created_at"`
+		PaidAt    *time.Time `db:"paid_at"`
+		Code      string     `db:"code"`
+	}
+
+	db := &DB{DB: mockDB, CoerceTypes: true}
+
+	dataRows := sqlmock.NewRows([]string{"id", "amount", "paid", "created_at", "paid_at", "code"}).
+		AddRow([]byte("1"), "12.50", []byte{1}, []byte("2021-10-01 08:30:00"), int64(1633077000), 1001).
+		AddRow("2", []byte("3"), int64(0), "2021-10-02T08:30:00Z", nil, 2.5)
+	mock.ExpectQuery("SELECT (.+) FROM orders").WillReturnRows(dataRows)
+
+	var orders []Orders
+	err = db.Query("SELECT * FROM orders").Scan(&orders)
+	if err != nil {
+		t.Fatalf("coerce types error %+v", err)
+	}
+
+	first := orders[0]
+	if first.Id != 1 || first.Amount != 12.5 || !first.Paid || first.Code != "1001" {
+		t.Fatalf("coerce types error %+v", first)
+	}
+	if !first.CreatedAt.Equal(time.Date(2021, 10, 1, 8, 30, 0, 0, time.UTC)) || first.PaidAt == nil || first.PaidAt.Unix() != 1633077000 {
+		t.Fatalf("coerce time error %+v", first)
+	}
+	if orders[1].Paid || orders[1].PaidAt != nil || orders[1].Code != "2.5" || orders[1].CreatedAt.Day() != 2 {
+		t.Fatalf("coerce types error %+v", orders[1])
+	}
+
+	dataRows = sqlmock.NewRows([]string{"id", "amount"}).AddRow(1, "abc")
+	mock.ExpectQuery("SELECT (.+) FROM orders").WillReturnRows(dataRows)
+
+	var order Orders
+	err = db.QueryRow("SELECT id,amount FROM orders").Scan(&order)
+	if err == nil || !strings.Contains(err.Error(), "amount") || !strings.Contains(err.Error(), "Amount") {
+		t.Fatalf("coerce error should name the column and field: %+v", err)
+	}
+	t.Logf("coerce error: %s", err)
+}
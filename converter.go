@@ -0,0 +1,230 @@
+package ploto
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// Converter converts the values of a Go type from and to the database
+type Converter interface {
+	// FromDB converts the driver value src into dest, a pointer to the registered type.
+	// src is nil for NULL, []byte src is only valid until FromDB returns.
+	FromDB(src interface{}, dest interface{}) error
+	// ToDB converts v, a value of the registered type, into the argument passed to the driver
+	ToDB(v interface{}) (driver.Value, error)
+}
+
+// ConverterFuncs adapts the functions to Converter
+type ConverterFuncs struct {
+	FromDBFunc func(src interface{}, dest interface{}) error
+	ToDBFunc   func(v interface{}) (driver.Value, error)
+}
+
+// FromDB calls FromDBFunc
+func (c ConverterFuncs) FromDB(src interface{}, dest interface{}) error {
+	return c.FromDBFunc(src, dest)
+}
+
+// ToDB calls ToDBFunc
+func (c ConverterFuncs) ToDB(v interface{}) (driver.Value, error) {
+	return c.ToDBFunc(v)
+}
+
+// ConverterRegistry the converters keyed by Go type
+type ConverterRegistry struct {
+	mu         sync.RWMutex
+	converters map[reflect.Type]Converter
+}
+
+// DefaultConverters the global converters, consulted after the converters of the DB
+var DefaultConverters = NewConverterRegistry()
+
+// NewConverterRegistry returns an empty ConverterRegistry
+func NewConverterRegistry() *ConverterRegistry {
+	return &ConverterRegistry{converters: make(map[reflect.Type]Converter)}
+}
+
+// Register registers the converter for the type of sample, e.g. Register(OrderStatus(""), converter)
+func (r *ConverterRegistry) Register(sample interface{}, converter Converter) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.converters[reflect.TypeOf(sample)] = converter
+}
+
+// Lookup returns the converter registered for the type
+func (r *ConverterRegistry) Lookup(typ reflect.Type) (Converter, bool) {
+	if r == nil {
+		return nil, false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	converter, ok := r.converters[typ]
+	return converter, ok
+}
+
+// RegisterConverter registers the global converter for the type of sample
+func RegisterConverter(sample interface{}, converter Converter) {
+	DefaultConverters.Register(sample, converter)
+}
+
+// lookupConverter returns the converter of the type, the converters of the DB take precedence
+func lookupConverter(converters *ConverterRegistry, typ reflect.Type) (Converter, bool) {
+	if converter, ok := converters.Lookup(typ); ok {
+		return converter, true
+	}
+	return DefaultConverters.Lookup(typ)
+}
+
+// lookupFieldConverter returns the converter of the scanned type, the *T without its own converter
+// is scanned through the converter of T, nil for NULL
+func lookupFieldConverter(converters *ConverterRegistry, typ reflect.Type) (Converter, bool) {
+	if converter, ok := lookupConverter(converters, typ); ok {
+		return converter, true
+	}
+	if typ.Kind() != reflect.Ptr {
+		return nil, false
+	}
+	converter, ok := lookupConverter(converters, typ.Elem())
+	if !ok {
+		return nil, false
+	}
+	return pointerConverter{converter: converter}, true
+}
+
+// pointerConverter converts the *T through the converter of T
+type pointerConverter struct {
+	converter Converter
+}
+
+// FromDB sets dest, a **T, to nil for NULL, otherwise to a new T converted by the converter of T
+func (c pointerConverter) FromDB(src interface{}, dest interface{}) error {
+	value := reflect.ValueOf(dest).Elem()
+	if src == nil {
+		value.Set(reflect.Zero(value.Type()))
+		return nil
+	}
+
+	elem := reflect.New(value.Type().Elem())
+	if err := c.converter.FromDB(src, elem.Interface()); err != nil {
+		return err
+	}
+	value.Set(elem)
+	return nil
+}
+
+// ToDB converts the value of the *T, NULL for nil
+func (c pointerConverter) ToDB(v interface{}) (driver.Value, error) {
+	value := reflect.ValueOf(v)
+	if !value.IsValid() || value.IsNil() {
+		return nil, nil
+	}
+	return c.converter.ToDB(value.Elem().Interface())
+}
+
+// convertArgs converts the args which have a registered converter
+func convertArgs(converters *ConverterRegistry, args []interface{}) ([]interface{}, error) {
+	var converted []interface{}
+
+	for i, arg := range args {
+		if arg == nil {
+			continue
+		}
+
+		value := reflect.ValueOf(arg)
+		converter, ok := lookupConverter(converters, value.Type())
+		if !ok && value.Kind() == reflect.Ptr {
+			if converter, ok = lookupConverter(converters, value.Type().Elem()); ok {
+				if value.IsNil() {
+					arg = nil
+				} else {
+					arg = value.Elem().Interface()
+				}
+			}
+		}
+		if !ok {
+			continue
+		}
+
+		if converted == nil {
+			converted = append(make([]interface{}, 0, len(args)), args...)
+		}
+		if arg == nil {
+			converted[i] = nil
+			continue
+		}
+
+		v, err := converter.ToDB(arg)
+		if err != nil {
+			return nil, err
+		}
+		converted[i] = v
+	}
+
+	if converted == nil {
+		return args, nil
+	}
+	return converted, nil
+}
+
+// fieldScanner scans the column into dest through the converter
+type fieldScanner struct {
+	converter Converter
+	dest      interface{}
+	// nullZero sets dest to the zero value for NULL without calling the converter
+	nullZero bool
+	// null the scanned column is NULL
+	null bool
+}
+
+// Scan implements the sql.Scanner interface
+func (fs *fieldScanner) Scan(src interface{}) error {
+	fs.null = src == nil
+	if fs.null && fs.nullZero {
+		dest := reflect.ValueOf(fs.dest).Elem()
+		dest.Set(reflect.Zero(dest.Type()))
+		return nil
+	}
+	return fs.converter.FromDB(src, fs.dest)
+}
+
+// jsonConverter decodes the JSON column into the field tagged db:"name,json", NULL for the zero value
+type jsonConverter struct{}
+
+// FromDB unmarshals the JSON document into dest
+func (jsonConverter) FromDB(src interface{}, dest interface{}) error {
+	value := reflect.ValueOf(dest).Elem()
+	value.Set(reflect.Zero(value.Type()))
+
+	switch v := src.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(v, dest)
+	case string:
+		return json.Unmarshal([]byte(v), dest)
+	default:
+		return fmt.Errorf("ploto: unsupported JSON column value %T", src)
+	}
+}
+
+// ToDB marshals v to the JSON document, the nil pointer, map or slice for NULL
+func (jsonConverter) ToDB(v interface{}) (driver.Value, error) {
+	value := reflect.ValueOf(v)
+	switch value.Kind() {
+	case reflect.Invalid:
+		return nil, nil
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface:
+		if value.IsNil() {
+			return nil, nil
+		}
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
@@ -0,0 +1,288 @@
+package ploto
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+)
+
+type tagSet []string
+
+var uuidBinaryConverter = ConverterFuncs{
+	FromDBFunc: func(src interface{}, dest interface{}) error {
+		b, ok := src.([]byte)
+		if !ok {
+			return fmt.Errorf("unsupported uuid value %T", src)
+		}
+		id, err := uuid.FromBytes(b)
+		*dest.(*uuid.UUID) = id
+		return err
+	},
+	ToDBFunc: func(v interface{}) (driver.Value, error) {
+		id := v.(uuid.UUID)
+		return id[:], nil
+	},
+}
+
+var tagSetConverter = ConverterFuncs{
+	FromDBFunc: func(src interface{}, dest interface{}) error {
+		switch v := src.(type) {
+		case nil:
+			*dest.(*tagSet) = nil
+		case []byte:
+			*dest.(*tagSet) = strings.Split(string(v), ",")
+		case string:
+			*dest.(*tagSet) = strings.Split(v, ",")
+		default:
+			return fmt.Errorf("unsupported set value %T", src)
+		}
+		return nil
+	},
+	ToDBFunc: func(v interface{}) (driver.Value, error) {
+		return strings.Join(v.(tagSet), ","), nil
+	},
+}
+
+func TestConverters(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+
+	type Items struct {
+		Id   uuid.UUID `db:"id"`
+		Tags tagSet    `db:"tags"`
+	}
+
+	id := uuid.New()
+	RegisterConverter(uuid.UUID{}, uuidBinaryConverter)
+	db := &DB{DB: mockDB, Converters: NewConverterRegistry()}
+	db.Converters.Register(tagSet{}, tagSetConverter)
+
+	dataRows := sqlmock.NewRows([]string{"id", "tags"}).
+		AddRow(id[:], "a,b,c").
+		AddRow(id[:], nil)
+	mock.ExpectQuery("SELECT (.+) FROM items WHERE id=?").WithArgs(id[:]).WillReturnRows(dataRows)
+
+	var items []Items
+	err = db.Query("SELECT id,tags FROM items WHERE id=?", id).Scan(&items)
+	if err != nil {
+		t.Fatalf("query with error %+v", err)
+	}
+
+	if len(items) != 2 || items[0].Id != id || len(items[0].Tags) != 3 || items[1].Tags != nil {
+		t.Fatalf("scan with converters error %+v", items)
+	}
+
+	mock.ExpectExec("update items").WithArgs("a,b", id[:]).WillReturnResult(sqlmock.NewResult(0, 1))
+	_, err = db.Exec("update items set tags=? where id=?", tagSet{"a", "b"}, &id)
+	if err != nil {
+		t.Fatalf("exec with error %+v", err)
+	}
+
+	dataRows = sqlmock.NewRows([]string{"tags"}).AddRow("x,y")
+	mock.ExpectQuery("SELECT tags FROM items").WillReturnRows(dataRows)
+
+	var tags tagSet
+	err = db.QueryRow("SELECT tags FROM items").Scan(&tags)
+	if err != nil || len(tags) != 2 {
+		t.Fatalf("scan scalar with converter error %+v %+v", err, tags)
+	}
+}
+
+func TestJSONColumn(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+
+	type Profile struct {
+		Age  int    `json:"age"`
+		City string `json:"city"`
+	}
+
+	type Events struct {
+		Id      int64                  `db:"id"`
+		Payload map[string]interface{} `db:"payload,json"`
+		Profile *Profile               `db:"profile,json"`
+	}
+
+	dataRows := sqlmock.NewRows([]string{"id", "payload", "profile"}).
+		AddRow(1, []byte(`{"name":"ploto"}`), []byte(`{"age":18,"city":"sz"}`)).
+		AddRow(2, nil, nil)
+	mock.ExpectQuery("SELECT (.+) FROM events").WillReturnRows(dataRows)
+
+	db := &DB{DB: mockDB}
+
+	var events []Events
+	err = db.Query("SELECT id,payload,profile FROM events").Scan(&events)
+	if err != nil {
+		t.Fatalf("query with error %+v", err)
+	}
+
+	if len(events) != 2 || events[0].Payload["name"] != "ploto" || events[0].Profile == nil || events[0].Profile.Age != 18 {
+		t.Fatalf("scan json column error %+v", events)
+	}
+	if events[1].Payload != nil || events[1].Profile != nil {
+		t.Fatalf("NULL json column should be nil %+v", events[1])
+	}
+
+	v, err := jsonConverter{}.ToDB(events[0].Profile)
+	if err != nil || v != `{"age":18,"city":"sz"}` {
+		t.Fatalf("json ToDB error %+v %+v", v, err)
+	}
+	if v, _ := (jsonConverter{}).ToDB(events[1].Payload); v != nil {
+		t.Fatalf("nil map should be NULL %+v", v)
+	}
+}
+
+type money struct {
+	Cents int64
+}
+
+var moneyConverter = ConverterFuncs{
+	FromDBFunc: func(src interface{}, dest interface{}) error {
+		var s string
+		switch v := src.(type) {
+		case []byte:
+			s = string(v)
+		case string:
+			s = v
+		default:
+			return fmt.Errorf("unsupported money value %T", src)
+		}
+		var yuan, fen int64
+		if _, err := fmt.Sscanf(s, "%d.%d", &yuan, &fen); err != nil {
+			return err
+		}
+		dest.(*money).Cents = yuan*100 + fen
+		return nil
+	},
+	ToDBFunc: func(v interface{}) (driver.Value, error) {
+		m := v.(money)
+		return fmt.Sprintf("%d.%02d", m.Cents/100, m.Cents%100), nil
+	},
+}
+
+func TestStructConverter(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+
+	converters := NewConverterRegistry()
+	converters.Register(money{}, moneyConverter)
+	db := &DB{DB: mockDB, Converters: converters}
+
+	type Buyer struct {
+		Name string `db:"name"`
+	}
+	type Orders struct {
+		Id     int64 `db:"id,pk,autoincr"`
+		Amount money `db:"amount"`
+		Buyer  Buyer `db:"buyer"`
+	}
+
+	mock.ExpectQuery("SELECT (.+) FROM orders").WillReturnRows(sqlmock.NewRows([]string{"id", "amount", "buyer_name"}).AddRow(1, "12.34", "ploto"))
+	var order Orders
+	if err := db.QueryRow("SELECT id,amount,buyer_name FROM orders").Strict().Scan(&order); err != nil {
+		t.Fatalf("Scan struct converter error %+v", err)
+	}
+	if order.Amount.Cents != 1234 || order.Buyer.Name != "ploto" {
+		t.Fatalf("Scan struct converter order %+v", order)
+	}
+
+	mock.ExpectExec("INSERT INTO `orders` \\(`amount`\\) VALUES \\(\\?\\)").WithArgs("12.34").WillReturnResult(sqlmock.NewResult(2, 1))
+	if _, err := db.Insert(context.Background(), "orders", &order); err != nil {
+		t.Fatalf("Insert struct converter error %+v", err)
+	}
+
+	// without the converter the struct field receives the prefixed columns only
+	mock.ExpectQuery("SELECT (.+) FROM orders").WillReturnRows(sqlmock.NewRows([]string{"id", "amount"}).AddRow(1, "12.34"))
+	err = (&DB{DB: mockDB}).QueryRow("SELECT id,amount FROM orders").Strict().Scan(&order)
+	if scanErr, ok := err.(*StrictScanError); !ok || len(scanErr.UnmappedColumns) != 1 || scanErr.UnmappedColumns[0] != "amount" {
+		t.Fatalf("Scan without struct converter error %+v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+type orderStatus int
+
+var orderStatusConverter = ConverterFuncs{
+	FromDBFunc: func(src interface{}, dest interface{}) error {
+		var s string
+		switch v := src.(type) {
+		case []byte:
+			s = string(v)
+		case string:
+			s = v
+		default:
+			return fmt.Errorf("unsupported status value %T", src)
+		}
+		switch s {
+		case "active":
+			*dest.(*orderStatus) = 1
+		case "closed":
+			*dest.(*orderStatus) = 2
+		default:
+			return fmt.Errorf("unknown status %s", s)
+		}
+		return nil
+	},
+	ToDBFunc: func(v interface{}) (driver.Value, error) {
+		return []string{"", "active", "closed"}[v.(orderStatus)], nil
+	},
+}
+
+func TestPointerConverter(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+
+	converters := NewConverterRegistry()
+	converters.Register(orderStatus(0), orderStatusConverter)
+	db := &DB{DB: mockDB, Converters: converters}
+
+	type Orders struct {
+		Id     int64        `db:"id"`
+		Status *orderStatus `db:"status"`
+	}
+
+	dataRows := sqlmock.NewRows([]string{"id", "status"}).AddRow(1, "active").AddRow(2, nil)
+	mock.ExpectQuery("SELECT (.+) FROM orders").WillReturnRows(dataRows)
+	var orders []Orders
+	if err := db.Query("SELECT id,status FROM orders").Scan(&orders); err != nil {
+		t.Fatalf("Scan nullable converted column error %+v", err)
+	}
+	if len(orders) != 2 || orders[0].Status == nil || *orders[0].Status != 1 || orders[1].Status != nil {
+		t.Fatalf("Scan nullable converted column %+v", orders)
+	}
+
+	mock.ExpectQuery("SELECT status FROM orders").WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("closed"))
+	var status *orderStatus
+	if err := db.QueryRow("SELECT status FROM orders").Scan(&status); err != nil || status == nil || *status != 2 {
+		t.Fatalf("Scan nullable converted scalar %+v %v", err, status)
+	}
+
+	mock.ExpectExec("update orders").WithArgs("closed", nil).WillReturnResult(sqlmock.NewResult(0, 1))
+	if _, err := db.Exec("update orders set status=? where status=?", status, orders[1].Status); err != nil {
+		t.Fatalf("Exec nullable converted args error %+v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
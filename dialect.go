@@ -7,6 +7,7 @@ import (
 	// "reflect"
 	// "strings"
 	"errors"
+	"strings"
 	"time"
 )
 
@@ -38,6 +39,8 @@ type DialectClientOption struct {
 	Pool           *DialectClientOptionPool `json:"pool"`
 	Charset        string                   `json:"charset"`
 	DialectOptions map[string]string        `json:"dialectOptions"`
+	// Rebind rebinds the ? placeholders to the native style of the dialect, e.g. @p1 for sqlserver
+	Rebind *bool `json:"rebind"`
 }
 
 type DialectDSN interface {
@@ -104,12 +107,16 @@ func (dialect *Dialect) CreateClient(database string) (db *DB, err error) {
 	}
 
 	//set db to the clients
-	db = &DB{DB: driverDB}
+	db = &DB{DB: driverDB, Dialect: dialector}
 
 	if config.Logging != nil {
 		db.LogSql = *config.Logging
 	}
 
+	if config.Rebind != nil {
+		db.Rebind = *config.Rebind
+	}
+
 	// logger.Info("create mysql db %s client success", database)
 
 	return db, err
@@ -123,6 +130,13 @@ func (dialect *Dialect) Use(database string) (db *DB) {
 	return client
 }
 
+// SetNameMapper set the NameMapper of all the clients
+func (dialect *Dialect) SetNameMapper(mapper NameMapper) {
+	for _, db := range dialect.Clients {
+		db.NameMapper = mapper
+	}
+}
+
 // GetClientConfig get the client config
 func (dialect *Dialect) getClientConfig(clientName string) (config *DialectClientOption) {
 
@@ -156,6 +170,10 @@ func (dialect *Dialect) getClientConfig(clientName string) (config *DialectClien
 		config.Logging = dialect.Configs.Default.Logging
 	}
 
+	if config.Rebind == nil {
+		config.Rebind = dialect.Configs.Default.Rebind
+	}
+
 	if config.DialectOptions == nil {
 		config.DialectOptions = dialect.Configs.Default.DialectOptions
 	}
@@ -186,3 +204,30 @@ func (dialect *Dialect) Close() error {
 	}
 	return nil
 }
+
+// isSQLServer reports whether the dialect is mssql or sqlserver
+func isSQLServer(dialect string) bool {
+	return dialect == "mssql" || dialect == "sqlserver"
+}
+
+// placeholderKind returns the placeholder kind of the dialect, '@' for @p1 and '?' for ?
+func placeholderKind(dialect string) byte {
+	if isSQLServer(dialect) {
+		return '@'
+	}
+	return '?'
+}
+
+// quoteIdentifier quotes the table or column name for the dialect, e.g. `db`.`users` and [dbo].[users]
+func quoteIdentifier(dialect string, name string) string {
+	open, close := "`", "`"
+	if isSQLServer(dialect) {
+		open, close = "[", "]"
+	}
+
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = open + strings.ReplaceAll(part, close, close+close) + close
+	}
+	return strings.Join(parts, ".")
+}
@@ -24,6 +24,7 @@ func getConfig() (config Configs) {
 		"default": {
 			"port": 3306,
 			"dialect": "mysql",
+			"rebind": true,
 			"pool": {
 				"maxIdleConns": 2,
 				"maxLeftTime": 60000, 
@@ -62,4 +63,8 @@ func TestGetClientConfig(t *testing.T) {
 		t.Errorf("get port failed %d", clientConfig.Port)
 	}
 
+	if clientConfig.Rebind == nil || !*clientConfig.Rebind {
+		t.Errorf("get default rebind failed %+v", clientConfig.Rebind)
+	}
+
 }
@@ -0,0 +1,36 @@
+package ploto
+
+import (
+	"context"
+	"reflect"
+)
+
+// AfterScanner is implemented by the models which need to be processed after scanned,
+// AfterScan is called by Scan, ScanSlice and the other scan functions for every scanned struct
+type AfterScanner interface {
+	AfterScan(ctx context.Context) error
+}
+
+// BeforeInserter is implemented by the models which need to be processed before inserted,
+// BeforeInsert is called by the struct-based write helpers
+type BeforeInserter interface {
+	BeforeInsert(ctx context.Context) error
+}
+
+var afterScannerType = reflect.TypeOf((*AfterScanner)(nil)).Elem()
+
+// callAfterScan calls AfterScan if the item implements AfterScanner
+func callAfterScan(ctx context.Context, item reflect.Value) error {
+	if hook, ok := item.Addr().Interface().(AfterScanner); ok {
+		return hook.AfterScan(ctx)
+	}
+	return nil
+}
+
+// callBeforeInsert calls BeforeInsert if the item implements BeforeInserter
+func callBeforeInsert(ctx context.Context, item reflect.Value) error {
+	if hook, ok := item.Addr().Interface().(BeforeInserter); ok {
+		return hook.BeforeInsert(ctx)
+	}
+	return nil
+}
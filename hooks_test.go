@@ -0,0 +1,47 @@
+package ploto
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+type hookCtxKey struct{}
+
+type HookUsers struct {
+	Id          int64  `db:"id"`
+	Name        string `db:"name"`
+	DisplayName string
+}
+
+func (u *HookUsers) AfterScan(ctx context.Context) error {
+	u.DisplayName = strings.ToUpper(u.Name) + ctx.Value(hookCtxKey{}).(string)
+	return nil
+}
+
+func TestAfterScan(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+
+	db := &DB{DB: mockDB}
+	ctx := context.WithValue(context.Background(), hookCtxKey{}, "!")
+
+	mock.ExpectQuery("SELECT (.+) FROM users").WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "a").AddRow(2, "b"))
+	var users []HookUsers
+	err = db.QueryContext(ctx, "SELECT id,name FROM users").Scan(&users)
+	if err != nil || len(users) != 2 || users[1].DisplayName != "B!" {
+		t.Fatalf("AfterScan with ScanSlice error %+v %+v", err, users)
+	}
+
+	mock.ExpectQuery("SELECT (.+) FROM users").WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "a"))
+	var user HookUsers
+	err = db.QueryRowContext(ctx, "SELECT id,name FROM users").Scan(&user)
+	if err != nil || user.DisplayName != "A!" {
+		t.Fatalf("AfterScan with Scan error %+v %+v", err, user)
+	}
+}
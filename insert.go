@@ -0,0 +1,415 @@
+package ploto
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// session the DB or Tx which the write helpers run on
+type session interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *RowResult
+	client() *DB
+}
+
+// client returns the db itself
+func (db *DB) client() *DB {
+	return db
+}
+
+// client returns the db of the transaction
+func (tx *Tx) client() *DB {
+	return tx.DB
+}
+
+// Insert inserts the model, a pointer to struct, into the table. The columns are the db tagged fields,
+// the omitempty fields with the zero value and the autoincr field are skipped. The generated id is set
+// to the autoincr field, by LastInsertId on mysql and OUTPUT INSERTED on sqlserver.
+func (db *DB) Insert(ctx context.Context, table string, model interface{}) (sql.Result, error) {
+	return insert(ctx, db, table, model)
+}
+
+// Insert inserts the model into the table in the transaction, see DB.Insert
+func (tx *Tx) Insert(ctx context.Context, table string, model interface{}) (sql.Result, error) {
+	return insert(ctx, tx, table, model)
+}
+
+// modelValue returns the struct the model points to
+func modelValue(model interface{}, method string) (reflect.Value, error) {
+	value := reflect.ValueOf(model)
+	if value.Kind() != reflect.Ptr || value.IsNil() || value.Elem().Kind() != reflect.Struct {
+		return reflect.Value{}, fmt.Errorf("ploto: %s model must be a pointer to struct, got %T", method, model)
+	}
+	return value.Elem(), nil
+}
+
+// insert inserts the model on the session
+func insert(ctx context.Context, sess session, table string, model interface{}) (sql.Result, error) {
+	item, err := modelValue(model, "Insert")
+	if err != nil {
+		return nil, err
+	}
+	if err := callBeforeInsert(ctx, item); err != nil {
+		return nil, err
+	}
+
+	dialect := sess.client().Dialect
+	columns, args, autoIncr, err := insertValues(sess.client().Converters, item)
+	if err != nil {
+		return nil, err
+	}
+
+	query := buildInsert(dialect, table, columns, autoIncr)
+
+	if autoIncr != nil && isSQLServer(dialect) {
+		id := fieldByIndexAlloc(item, autoIncr.index)
+		if err := sess.QueryRowContext(ctx, query, args...).Scan(id.Addr().Interface()); err != nil {
+			return nil, err
+		}
+		return driver.RowsAffected(1), nil
+	}
+
+	result, err := sess.ExecContext(ctx, query, args...)
+	if err != nil || autoIncr == nil {
+		return result, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return result, err
+	}
+	return result, setInsertID(fieldByIndexAlloc(item, autoIncr.index), id)
+}
+
+// writeFields returns the columns of the struct type written by the write helpers,
+// the composite struct fields are written only if their types have a registered converter
+func writeFields(converters *ConverterRegistry, typ reflect.Type) []*structField {
+	columns := getStructInfo(typ).columns
+	fields := make([]*structField, 0, len(columns))
+	for _, f := range columns {
+		if f.composite {
+			if _, ok := lookupFieldConverter(converters, f.field.Type); !ok {
+				continue
+			}
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+// insertValues returns the columns and the args written by Insert, the omitempty fields with the zero value
+// and the autoincr field are skipped, the autoincr field is returned
+func insertValues(converters *ConverterRegistry, item reflect.Value) ([]string, []interface{}, *structField, error) {
+	var columns []string
+	var args []interface{}
+	var autoIncr *structField
+	for _, f := range writeFields(converters, item.Type()) {
+		if f.autoIncr {
+			autoIncr = f
+			continue
+		}
+		value := fieldByIndexNoAlloc(item, f.index)
+		if f.omitEmpty && (!value.IsValid() || value.IsZero()) {
+			continue
+		}
+		arg, err := fieldArg(item, f)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		columns = append(columns, f.name)
+		args = append(args, arg)
+	}
+	return columns, args, autoIncr, nil
+}
+
+// buildInsert builds the INSERT statement of the columns, the autoincr column is returned by OUTPUT INSERTED on sqlserver
+func buildInsert(dialect string, table string, columns []string, autoIncr *structField) string {
+	var b strings.Builder
+	b.WriteString("INSERT INTO ")
+	b.WriteString(quoteIdentifier(dialect, table))
+
+	if len(columns) > 0 {
+		b.WriteString(" (")
+		writeColumns(&b, dialect, columns)
+		b.WriteByte(')')
+	} else if !isSQLServer(dialect) {
+		b.WriteString(" ()")
+	}
+
+	if autoIncr != nil && isSQLServer(dialect) {
+		b.WriteString(" OUTPUT INSERTED.")
+		b.WriteString(quoteIdentifier(dialect, autoIncr.name))
+	}
+
+	if len(columns) == 0 {
+		if isSQLServer(dialect) {
+			b.WriteString(" DEFAULT VALUES")
+		} else {
+			b.WriteString(" VALUES ()")
+		}
+		return b.String()
+	}
+
+	b.WriteString(" VALUES ")
+	writeValues(&b, placeholderKind(dialect), 1, len(columns))
+	return b.String()
+}
+
+// writeColumns writes the quoted columns separated by comma
+func writeColumns(b *strings.Builder, dialect string, columns []string) {
+	for i, column := range columns {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(quoteIdentifier(dialect, column))
+	}
+}
+
+// writeValues writes the row of size placeholders, numbered from n for @pN
+func writeValues(b *strings.Builder, kind byte, n int, size int) {
+	b.WriteByte('(')
+	for i := 0; i < size; i++ {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		writePlaceholder(b, kind, n+i)
+	}
+	b.WriteByte(')')
+}
+
+// setInsertID sets the generated id to the autoincr field
+func setInsertID(field reflect.Value, id int64) error {
+	if field.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			field.Set(reflect.New(field.Type().Elem()))
+		}
+		field = field.Elem()
+	}
+
+	switch field.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		field.SetInt(id)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		field.SetUint(uint64(id))
+	default:
+		return fmt.Errorf("ploto: can not set the insert id to %s", field.Type())
+	}
+	return nil
+}
+
+// BulkOptions the options of the bulk write helpers
+type BulkOptions struct {
+	// BatchSize the max rows of a statement, no limit if 0
+	BatchSize int
+	// MaxPacket the max estimated bytes of a statement on mysql, 4MB if 0, keep it under max_allowed_packet
+	MaxPacket int
+	// MaxParams the max parameters of a statement, 65535 on mysql if 0, at most 2098 on sqlserver
+	MaxParams int
+}
+
+const (
+	defaultMaxPacket = 4 << 20
+	mysqlMaxParams   = 65535
+	// sp_executesql takes 2100 parameters, including @stmt and @params
+	sqlServerMaxParams    = 2098
+	sqlServerMaxValueRows = 1000
+)
+
+// bulkDefault the omitempty zero value of the bulk rows, written as DEFAULT
+type bulkDefault struct{}
+
+// BulkInsert inserts the rows, a slice of structs or struct pointers, with the multi-row INSERT statements.
+// The rows are split into batches by BulkOptions, opts can be nil. The omitempty fields with the zero value
+// are written as DEFAULT, the autoincr fields are skipped and not set. The batches are executed one by one,
+// use Tx.BulkInsert to insert all or nothing.
+func (db *DB) BulkInsert(ctx context.Context, table string, rows interface{}, opts *BulkOptions) (sql.Result, error) {
+	return bulkInsert(ctx, db, table, rows, opts)
+}
+
+// BulkInsert inserts the rows in the transaction, see DB.BulkInsert
+func (tx *Tx) BulkInsert(ctx context.Context, table string, rows interface{}, opts *BulkOptions) (sql.Result, error) {
+	return bulkInsert(ctx, tx, table, rows, opts)
+}
+
+// bulkInsert inserts the rows on the session
+func bulkInsert(ctx context.Context, sess session, table string, rows interface{}, opts *BulkOptions) (sql.Result, error) {
+	columns, values, err := bulkValues(ctx, sess.client().Converters, rows, "BulkInsert", true)
+	if err != nil {
+		return nil, err
+	}
+
+	dialect := sess.client().Dialect
+	var header strings.Builder
+	header.WriteString("INSERT INTO ")
+	header.WriteString(quoteIdentifier(dialect, table))
+	header.WriteString(" (")
+	writeColumns(&header, dialect, columns)
+	header.WriteString(") VALUES ")
+
+	var affected int64
+	for _, batch := range splitBatches(dialect, opts, header.Len(), len(columns), values) {
+		var b strings.Builder
+		b.WriteString(header.String())
+		args := writeRows(&b, placeholderKind(dialect), batch)
+
+		result, err := sess.ExecContext(ctx, b.String(), args...)
+		if err != nil {
+			return driver.RowsAffected(affected), err
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return driver.RowsAffected(affected), err
+		}
+		affected += n
+	}
+	return driver.RowsAffected(affected), nil
+}
+
+// bulkValues returns the columns and the values of every row, BeforeInsert is called for every row,
+// the omitempty fields with the zero value are bulkDefault if omitEmpty
+func bulkValues(ctx context.Context, converters *ConverterRegistry, rows interface{}, method string, omitEmpty bool) ([]string, [][]interface{}, error) {
+	slice := reflect.ValueOf(rows)
+	if slice.Kind() == reflect.Ptr {
+		slice = slice.Elem()
+	}
+	if slice.Kind() != reflect.Slice {
+		return nil, nil, fmt.Errorf("ploto: %s rows must be a slice of structs, got %T", method, rows)
+	}
+	elemType := indirectType(slice.Type().Elem())
+	if elemType.Kind() != reflect.Struct {
+		return nil, nil, fmt.Errorf("ploto: %s rows must be a slice of structs, got %T", method, rows)
+	}
+
+	var fields []*structField
+	var columns []string
+	for _, f := range writeFields(converters, elemType) {
+		if !f.autoIncr {
+			fields = append(fields, f)
+			columns = append(columns, f.name)
+		}
+	}
+	if len(columns) == 0 {
+		return nil, nil, fmt.Errorf("ploto: %s %s has no columns", method, elemType)
+	}
+
+	values := make([][]interface{}, 0, slice.Len())
+	for i := 0; i < slice.Len(); i++ {
+		item := reflect.Indirect(slice.Index(i))
+		if !item.IsValid() {
+			return nil, nil, fmt.Errorf("ploto: %s rows[%d] is nil", method, i)
+		}
+		if err := callBeforeInsert(ctx, item); err != nil {
+			return nil, nil, err
+		}
+
+		row := make([]interface{}, len(fields))
+		for j, f := range fields {
+			value := fieldByIndexNoAlloc(item, f.index)
+			if omitEmpty && f.omitEmpty && (!value.IsValid() || value.IsZero()) {
+				row[j] = bulkDefault{}
+				continue
+			}
+			arg, err := fieldArg(item, f)
+			if err != nil {
+				return nil, nil, err
+			}
+			row[j] = arg
+		}
+		values = append(values, row)
+	}
+	return columns, values, nil
+}
+
+// splitBatches splits the rows into the batches within the limits of the dialect and the options
+func splitBatches(dialect string, opts *BulkOptions, headerSize int, columns int, values [][]interface{}) [][][]interface{} {
+	if opts == nil {
+		opts = &BulkOptions{}
+	}
+
+	maxRows := opts.BatchSize
+	maxParams := opts.MaxParams
+	maxPacket := 0
+	if isSQLServer(dialect) {
+		if maxParams <= 0 || maxParams > sqlServerMaxParams {
+			maxParams = sqlServerMaxParams
+		}
+		// the table value constructor allows 1000 rows at most
+		if maxRows <= 0 || maxRows > sqlServerMaxValueRows {
+			maxRows = sqlServerMaxValueRows
+		}
+	} else {
+		if maxParams <= 0 {
+			maxParams = mysqlMaxParams
+		}
+		maxPacket = opts.MaxPacket
+		if maxPacket <= 0 {
+			maxPacket = defaultMaxPacket
+		}
+	}
+	if rows := maxParams / columns; rows > 0 && (maxRows <= 0 || rows < maxRows) {
+		maxRows = rows
+	}
+	if maxRows <= 0 {
+		maxRows = 1
+	}
+
+	var batches [][][]interface{}
+	start, size := 0, headerSize
+	for i, row := range values {
+		n := rowSize(row)
+		if i > start && (i-start >= maxRows || maxPacket > 0 && size+n > maxPacket) {
+			batches = append(batches, values[start:i])
+			start, size = i, headerSize
+		}
+		size += n
+	}
+	if start < len(values) {
+		batches = append(batches, values[start:])
+	}
+	return batches
+}
+
+// rowSize returns the estimated bytes of the row in the statement
+func rowSize(row []interface{}) int {
+	size := 3
+	for _, v := range row {
+		switch arg := v.(type) {
+		case string:
+			size += len(arg)
+		case []byte:
+			size += len(arg)
+		default:
+			size += 8
+		}
+		size += 4
+	}
+	return size
+}
+
+// writeRows writes the rows of placeholders (?,?),(?,DEFAULT) and returns the args
+func writeRows(b *strings.Builder, kind byte, rows [][]interface{}) []interface{} {
+	args := make([]interface{}, 0, len(rows)*len(rows[0]))
+	for i, row := range rows {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteByte('(')
+		for j, v := range row {
+			if j > 0 {
+				b.WriteByte(',')
+			}
+			if _, ok := v.(bulkDefault); ok {
+				b.WriteString("DEFAULT")
+				continue
+			}
+			args = append(args, v)
+			writePlaceholder(b, kind, len(args))
+		}
+		b.WriteByte(')')
+	}
+	return args
+}
@@ -0,0 +1,212 @@
+package ploto
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+type InsertUsers struct {
+	Id        int64     `db:"id,pk,autoincr"`
+	Name      string    `db:"name"`
+	Email     string    `db:"email,omitempty"`
+	Tags      []string  `db:"tags,json"`
+	CreatedAt time.Time `db:"created_at"`
+	Ignored   string    `db:"-"`
+	Comment   string
+}
+
+func (u *InsertUsers) BeforeInsert(ctx context.Context) error {
+	if u.Name == "" {
+		return errors.New("name required")
+	}
+	if u.CreatedAt.IsZero() {
+		u.CreatedAt = time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return nil
+}
+
+func TestInsert(t *testing.T) {
+	mockDB, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	db := &DB{DB: mockDB}
+	ctx := context.Background()
+	createdAt := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectExec("INSERT INTO `users` (`name`,`tags`,`created_at`) VALUES (?,?,?)").WithArgs("ploto", `["a"]`, createdAt).WillReturnResult(sqlmock.NewResult(10, 1))
+	user := InsertUsers{Name: "ploto", Tags: []string{"a"}}
+	if _, err := db.Insert(ctx, "users", &user); err != nil {
+		t.Fatalf("Insert error %+v", err)
+	}
+	if user.Id != 10 || user.CreatedAt != createdAt {
+		t.Fatalf("Insert user %+v", user)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `test`.`users` (`name`,`email`,`tags`,`created_at`) VALUES (?,?,?,?)").WithArgs("feiin", "feiin@test.com", nil, createdAt).WillReturnResult(sqlmock.NewResult(11, 1))
+	mock.ExpectCommit()
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Begin error %+v", err)
+	}
+	user = InsertUsers{Name: "feiin", Email: "feiin@test.com"}
+	if _, err := tx.Insert(ctx, "test.users", &user); err != nil {
+		t.Fatalf("Tx Insert error %+v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit error %+v", err)
+	}
+	if user.Id != 11 {
+		t.Fatalf("Tx Insert user %+v", user)
+	}
+
+	if _, err := db.Insert(ctx, "users", &InsertUsers{}); err == nil || err.Error() != "name required" {
+		t.Fatalf("Insert BeforeInsert error %+v", err)
+	}
+	if _, err := db.Insert(ctx, "users", user); err == nil {
+		t.Fatalf("Insert non-pointer model should be error")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestInsertSQLServer(t *testing.T) {
+	mockDB, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	db := &DB{DB: mockDB, Dialect: "sqlserver"}
+	ctx := context.Background()
+
+	mock.ExpectQuery("INSERT INTO [dbo].[users] ([name],[tags],[created_at]) OUTPUT INSERTED.[id] VALUES (@p1,@p2,@p3)").WithArgs("ploto", nil, sqlmock.AnyArg()).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	user := InsertUsers{Name: "ploto"}
+	result, err := db.Insert(ctx, "dbo.users", &user)
+	if err != nil {
+		t.Fatalf("Insert error %+v", err)
+	}
+	if affected, _ := result.RowsAffected(); affected != 1 || user.Id != 7 {
+		t.Fatalf("Insert user %+v affected %d", user, affected)
+	}
+
+	type Logs struct {
+		Id *int64 `db:"id,autoincr"`
+	}
+	mock.ExpectQuery("INSERT INTO [logs] OUTPUT INSERTED.[id] DEFAULT VALUES").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(8))
+	var log Logs
+	if _, err := db.Insert(ctx, "logs", &log); err != nil {
+		t.Fatalf("Insert default values error %+v", err)
+	}
+	if log.Id == nil || *log.Id != 8 {
+		t.Fatalf("Insert default values id %+v", log.Id)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestBulkInsert(t *testing.T) {
+	mockDB, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	db := &DB{DB: mockDB}
+	ctx := context.Background()
+	createdAt := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	users := []InsertUsers{{Name: "a"}, {Name: "b", Email: "b@test.com"}, {Name: "c"}}
+	mock.ExpectExec("INSERT INTO `users` (`name`,`email`,`tags`,`created_at`) VALUES (?,DEFAULT,?,?),(?,?,?,?)").
+		WithArgs("a", nil, createdAt, "b", "b@test.com", nil, createdAt).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("INSERT INTO `users` (`name`,`email`,`tags`,`created_at`) VALUES (?,DEFAULT,?,?)").
+		WithArgs("c", nil, createdAt).WillReturnResult(sqlmock.NewResult(0, 1))
+	result, err := db.BulkInsert(ctx, "users", users, &BulkOptions{BatchSize: 2})
+	if err != nil {
+		t.Fatalf("BulkInsert error %+v", err)
+	}
+	if affected, _ := result.RowsAffected(); affected != 3 || users[2].CreatedAt != createdAt {
+		t.Fatalf("BulkInsert affected %d users %+v", affected, users)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `users` (`name`,`email`,`tags`,`created_at`) VALUES (?,DEFAULT,?,?)").
+		WithArgs("d", nil, createdAt).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Begin error %+v", err)
+	}
+	if _, err := tx.BulkInsert(ctx, "users", []*InsertUsers{{Name: "d"}}, nil); err != nil {
+		t.Fatalf("Tx BulkInsert error %+v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit error %+v", err)
+	}
+
+	if result, err := db.BulkInsert(ctx, "users", []InsertUsers{}, nil); err != nil {
+		t.Fatalf("BulkInsert empty rows error %+v", err)
+	} else if affected, _ := result.RowsAffected(); affected != 0 {
+		t.Fatalf("BulkInsert empty rows affected %d", affected)
+	}
+	if _, err := db.BulkInsert(ctx, "users", InsertUsers{}, nil); err == nil {
+		t.Fatalf("BulkInsert non-slice rows should be error")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestSplitBatches(t *testing.T) {
+	values := make([][]interface{}, 2500)
+	for i := range values {
+		values[i] = []interface{}{i, "name"}
+	}
+
+	cases := []struct {
+		dialect string
+		opts    *BulkOptions
+		sizes   []int
+	}{
+		// 2098 params / 2 columns = 1049 rows, 1000 rows by the table value constructor
+		{"sqlserver", nil, []int{1000, 1000, 500}},
+		{"sqlserver", &BulkOptions{MaxParams: 1000}, []int{500, 500, 500, 500, 500}},
+		{"mysql", nil, []int{2500}},
+		{"mysql", &BulkOptions{BatchSize: 1000}, []int{1000, 1000, 500}},
+		// 100 + 1000 * 23 bytes
+		{"mysql", &BulkOptions{MaxPacket: 23100}, []int{1000, 1000, 500}},
+	}
+
+	for _, c := range cases {
+		batches := splitBatches(c.dialect, c.opts, 100, 2, values)
+		var sizes []int
+		for _, batch := range batches {
+			sizes = append(sizes, len(batch))
+		}
+		if !reflect.DeepEqual(sizes, c.sizes) {
+			t.Fatalf("splitBatches %s %+v sizes %+v", c.dialect, c.opts, sizes)
+		}
+	}
+	// 3 columns * 700 rows = 2100 params exceeds the limit of sp_executesql
+	values = make([][]interface{}, 700)
+	for i := range values {
+		values[i] = []interface{}{i, "name", "email"}
+	}
+	var sizes []int
+	for _, batch := range splitBatches("sqlserver", nil, 100, 3, values) {
+		sizes = append(sizes, len(batch))
+	}
+	if !reflect.DeepEqual(sizes, []int{699, 1}) {
+		t.Fatalf("splitBatches sqlserver 3 columns sizes %+v", sizes)
+	}
+}
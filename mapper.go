@@ -0,0 +1,122 @@
+package ploto
+
+import (
+	"reflect"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+)
+
+// NameMapper maps the struct fields and the result columns to the names they are matched by.
+// The db tag always takes precedence, NameMapper is consulted for the other fields.
+// The column mapping is cached per comparable mapper, the other mappers, e.g. a struct holding a func,
+// compute it on every scan.
+type NameMapper interface {
+	// FieldName returns the name the struct field is matched by
+	FieldName(field reflect.StructField) string
+	// ColumnName returns the name the column is matched by
+	ColumnName(column string) string
+}
+
+var (
+	// DefaultNameMapper matches the column with the first letter upper-cased to the field name,
+	// e.g. name => Name
+	DefaultNameMapper NameMapper = titleNameMapper{}
+
+	// ExactNameMapper matches the column to the field name exactly
+	ExactNameMapper NameMapper = exactNameMapper{}
+
+	// SnakeCaseNameMapper matches the snake_case column to the CamelCase field name,
+	// e.g. created_time => CreatedTime, user_id => UserID
+	SnakeCaseNameMapper NameMapper = snakeCaseNameMapper{}
+
+	// LowerCaseNameMapper matches the column to the field name case-insensitively
+	LowerCaseNameMapper NameMapper = lowerCaseNameMapper{}
+)
+
+// JSONTagNameMapper matches the column to the json tag of the field,
+// the fields without json tag fall back to the mapper
+func JSONTagNameMapper(mapper NameMapper) NameMapper {
+	if mapper == nil {
+		mapper = DefaultNameMapper
+	}
+	return jsonTagNameMapper{fallback: mapper}
+}
+
+type titleNameMapper struct{}
+
+func (titleNameMapper) FieldName(field reflect.StructField) string {
+	return field.Name
+}
+
+func (titleNameMapper) ColumnName(column string) string {
+	return cases.Title(language.Und, cases.NoLower).String(column)
+}
+
+type exactNameMapper struct{}
+
+func (exactNameMapper) FieldName(field reflect.StructField) string {
+	return field.Name
+}
+
+func (exactNameMapper) ColumnName(column string) string {
+	return column
+}
+
+type snakeCaseNameMapper struct{}
+
+func (snakeCaseNameMapper) FieldName(field reflect.StructField) string {
+	return toSnakeCase(field.Name)
+}
+
+func (snakeCaseNameMapper) ColumnName(column string) string {
+	return strings.ToLower(column)
+}
+
+type lowerCaseNameMapper struct{}
+
+func (lowerCaseNameMapper) FieldName(field reflect.StructField) string {
+	return strings.ToLower(field.Name)
+}
+
+func (lowerCaseNameMapper) ColumnName(column string) string {
+	return strings.ToLower(column)
+}
+
+type jsonTagNameMapper struct {
+	fallback NameMapper
+}
+
+func (m jsonTagNameMapper) FieldName(field reflect.StructField) string {
+	tag := strings.Split(field.Tag.Get("json"), ",")[0]
+	if tag == "" || tag == "-" {
+		return m.fallback.FieldName(field)
+	}
+	return m.fallback.ColumnName(tag)
+}
+
+func (m jsonTagNameMapper) ColumnName(column string) string {
+	return m.fallback.ColumnName(column)
+}
+
+// toSnakeCase CamelCase => camel_case, UserID => user_id
+func toSnakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	b.Grow(len(name) + 4)
+
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1]) ||
+				(i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(runes[i-1]))) {
+				b.WriteByte('_')
+			}
+			b.WriteRune(unicode.ToLower(r))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
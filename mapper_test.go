@@ -0,0 +1,133 @@
+package ploto
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+type LegacyUsers struct {
+	Id          int64
+	UserName    string `json:"nick"`
+	CreatedTime string
+	UpdatedTime string
+}
+
+func TestToSnakeCase(t *testing.T) {
+	cases := map[string]string{
+		"Id":          "id",
+		"CreatedTime": "created_time",
+		"UserID":      "user_id",
+		"HTTPServer":  "http_server",
+		"Address2":    "address2",
+	}
+
+	for name, expected := range cases {
+		if v := toSnakeCase(name); v != expected {
+			t.Fatalf("toSnakeCase %s should be %s, got %s", name, expected, v)
+		}
+	}
+}
+
+func TestNameMappers(t *testing.T) {
+	testCases := []struct {
+		mapper  NameMapper
+		columns []string
+	}{
+		{SnakeCaseNameMapper, []string{"id", "user_name", "created_time", "updated_time"}},
+		{LowerCaseNameMapper, []string{"ID", "username", "createdtime", "UPDATEDTIME"}},
+		{ExactNameMapper, []string{"Id", "UserName", "CreatedTime", "UpdatedTime"}},
+		{JSONTagNameMapper(SnakeCaseNameMapper), []string{"id", "nick", "created_time", "updated_time"}},
+	}
+
+	for _, tc := range testCases {
+		mockDB, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		dataRows := sqlmock.NewRows(tc.columns).
+			AddRow(1, "1111", "2021-10-01 00:00:00", "2021-10-02 00:00:00")
+		mock.ExpectQuery("SELECT (.+) FROM users").WillReturnRows(dataRows)
+
+		db := &DB{DB: mockDB, NameMapper: tc.mapper}
+
+		var users []LegacyUsers
+		err = db.Query("SELECT * FROM users").Scan(&users)
+		if err != nil {
+			t.Fatalf("query with error %+v", err)
+		}
+
+		if len(users) != 1 || users[0].Id != 1 || users[0].UserName != "1111" || users[0].UpdatedTime != "2021-10-02 00:00:00" {
+			t.Fatalf("scan with %T error %+v", tc.mapper, users)
+		}
+		mockDB.Close()
+	}
+}
+
+func TestDefaultNameMapper(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+
+	dataRows := sqlmock.NewRows([]string{"id", "created_time"}).
+		AddRow(1, "2021-10-01 00:00:00")
+	mock.ExpectQuery("SELECT (.+) FROM users").WillReturnRows(dataRows)
+
+	db := &DB{DB: mockDB}
+
+	var user LegacyUsers
+	err = db.QueryRow("SELECT id,created_time FROM users").Scan(&user)
+	if err != nil {
+		t.Fatalf("query with error %+v", err)
+	}
+
+	if user.Id != 1 || user.CreatedTime != "" {
+		t.Fatalf("scan with default mapper error %+v", user)
+	}
+}
+
+// funcNameMapper the mapper holding funcs, which can not be a map key
+type funcNameMapper struct {
+	column func(string) string
+}
+
+func (m funcNameMapper) FieldName(field reflect.StructField) string {
+	return field.Name
+}
+
+func (m funcNameMapper) ColumnName(column string) string {
+	return m.column(column)
+}
+
+func TestUnhashableNameMapper(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+
+	mappers := []NameMapper{
+		funcNameMapper{column: func(column string) string { return strings.ToUpper(column[:1]) + column[1:] }},
+		JSONTagNameMapper(&funcNameMapper{column: strings.Title}),
+	}
+	for i, mapper := range mappers {
+		db := &DB{DB: mockDB, NameMapper: mapper}
+		mock.ExpectQuery("SELECT (.+) FROM users").WillReturnRows(sqlmock.NewRows([]string{"id", "nick"}).AddRow(1, "ploto"))
+		var user LegacyUsers
+		if err := db.QueryRow("SELECT id,nick FROM users").Scan(&user); err != nil {
+			t.Fatalf("Scan with mapper %d error %+v", i, err)
+		}
+		if user.Id != 1 {
+			t.Fatalf("Scan with mapper %d user %+v", i, user)
+		}
+	}
+
+	if isHashable(mappers[0]) || !isHashable(mappers[1]) || !isHashable(DefaultNameMapper) {
+		t.Fatalf("isHashable mismatch")
+	}
+}
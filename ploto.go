@@ -3,8 +3,10 @@ package ploto
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
 
-	"github.com/feiin/sqlstring"
 	"github.com/google/uuid"
 )
 
@@ -12,16 +14,50 @@ type DB struct {
 	*sql.DB
 	LogSql bool
 	logger LoggerInterface
+	// Dialect the dialect of the db, e.g. mysql, mssql and sqlserver
+	Dialect string
+	// Rebind rebinds the ? placeholders to the native style of the Dialect before executing
+	Rebind bool
+	// NameMapper maps the untagged struct fields to the columns, DefaultNameMapper if nil
+	NameMapper NameMapper
+	// Converters the converters of the db, consulted before DefaultConverters
+	Converters *ConverterRegistry
+	// CoerceTypes converts the mismatched column types when scanning, e.g. numeric strings,
+	// datetime strings, 0/1 to bool and Unix timestamps to time.Time
+	CoerceTypes bool
+	// NullZero scans NULL as the zero value of the non-nullable fields and scalars, e.g. int and string,
+	// the fields tagged db:"name,nullzero" always do. NULL is still an error in strict mode.
+	NullZero bool
 }
 
 type RowsResult struct {
 	*sql.Rows
 	LastError error
+	scanner   *scanner
+	// plan the cached column mapping of ScanRow
+	plan     *scanPlan
+	planType reflect.Type
+	// row the index of the row scanned by ScanRow
+	row int
 }
 
 type RowResult struct {
 	rows      *sql.Rows
 	LastError error
+	scanner   *scanner
+}
+
+// newScanner returns the scanner with the options of the db
+func (db *DB) newScanner(ctx context.Context) *scanner {
+	return &scanner{ctx: ctx, mapper: db.NameMapper, converters: db.Converters, coerce: db.CoerceTypes, nullZero: db.NullZero}
+}
+
+// getScanner returns the scanner of the result, defaultScanner if not set
+func getScanner(s *scanner) *scanner {
+	if s == nil {
+		return defaultScanner
+	}
+	return s
 }
 
 //RawDB return the *sql.DB
@@ -40,11 +76,15 @@ func (db *DB) Query(query string, args ...interface{}) *RowsResult {
 // QueryContext executes a query that returns RowsResult, typically a SELECT.
 // The args are for any placeholder parameters in the query.
 func (db *DB) QueryContext(ctx context.Context, query string, args ...interface{}) *RowsResult {
+	query, args, err := db.bindArgs(query, args)
+	if err != nil {
+		return &RowsResult{LastError: err, scanner: db.newScanner(ctx)}
+	}
 	if db.LogSql {
-		db.logger.Info(ctx, "QueryContext sql:%s", sqlstring.Format(query, args...))
+		db.logger.Info(ctx, "QueryContext sql:%s", formatQuery(db.Dialect, query, args...))
 	}
 	rs, err := db.DB.QueryContext(ctx, query, args...)
-	return &RowsResult{rs, err}
+	return &RowsResult{Rows: rs, LastError: err, scanner: db.newScanner(ctx)}
 }
 
 // QueryRowContext executes a query that is expected to return at most one row.
@@ -54,11 +94,15 @@ func (db *DB) QueryContext(ctx context.Context, query string, args ...interface{
 // Otherwise, the *Row's Scan scans the first selected row and discards
 // the rest.
 func (db *DB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *RowResult {
+	query, args, err := db.bindArgs(query, args)
+	if err != nil {
+		return &RowResult{LastError: err, scanner: db.newScanner(ctx)}
+	}
 	if db.LogSql {
-		db.logger.Info(ctx, "QueryRowContext sql:%s", sqlstring.Format(query, args...))
+		db.logger.Info(ctx, "QueryRowContext sql:%s", formatQuery(db.Dialect, query, args...))
 	}
 	rows, err := db.DB.QueryContext(ctx, query, args...)
-	return &RowResult{rows: rows, LastError: err}
+	return &RowResult{rows: rows, LastError: err, scanner: db.newScanner(ctx)}
 }
 
 // QueryRow executes a query that is expected to return at most one row.
@@ -80,13 +124,61 @@ func (db *DB) Exec(query string, args ...interface{}) (sql.Result, error) {
 // ExecContext executes a query without returning any rows.
 // The args are for any placeholder parameters in the query.
 func (db *DB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	query, args, err := db.bindArgs(query, args)
+	if err != nil {
+		return nil, err
+	}
 	if db.LogSql {
-		db.logger.Info(ctx, "ExecContext sql:%s", sqlstring.Format(query, args...))
+		db.logger.Info(ctx, "ExecContext sql:%s", formatQuery(db.Dialect, query, args...))
 	}
 
 	return db.DB.ExecContext(ctx, query, args...)
 }
 
+// NamedQuery executes a query with the named parameters :name or @name,
+// arg is a map[string]interface{} or a struct bound by the db tags
+func (db *DB) NamedQuery(query string, arg interface{}) *RowsResult {
+	return db.NamedQueryContext(context.Background(), query, arg)
+}
+
+// NamedQueryContext executes a query with the named parameters :name or @name,
+// arg is a map[string]interface{} or a struct bound by the db tags
+func (db *DB) NamedQueryContext(ctx context.Context, query string, arg interface{}) *RowsResult {
+	query, args, err := bindNamed(db.Dialect, query, arg, db.NameMapper)
+	if err != nil {
+		return &RowsResult{LastError: err, scanner: db.newScanner(ctx)}
+	}
+	return db.QueryContext(ctx, query, args...)
+}
+
+// NamedQueryRow executes a query with the named parameters that is expected to return at most one row.
+func (db *DB) NamedQueryRow(query string, arg interface{}) *RowResult {
+	return db.NamedQueryRowContext(context.Background(), query, arg)
+}
+
+// NamedQueryRowContext executes a query with the named parameters that is expected to return at most one row.
+func (db *DB) NamedQueryRowContext(ctx context.Context, query string, arg interface{}) *RowResult {
+	query, args, err := bindNamed(db.Dialect, query, arg, db.NameMapper)
+	if err != nil {
+		return &RowResult{LastError: err, scanner: db.newScanner(ctx)}
+	}
+	return db.QueryRowContext(ctx, query, args...)
+}
+
+// NamedExec executes a query with the named parameters without returning any rows.
+func (db *DB) NamedExec(query string, arg interface{}) (sql.Result, error) {
+	return db.NamedExecContext(context.Background(), query, arg)
+}
+
+// NamedExecContext executes a query with the named parameters without returning any rows.
+func (db *DB) NamedExecContext(ctx context.Context, query string, arg interface{}) (sql.Result, error) {
+	query, args, err := bindNamed(db.Dialect, query, arg, db.NameMapper)
+	if err != nil {
+		return nil, err
+	}
+	return db.ExecContext(ctx, query, args...)
+}
+
 //Close returns the connection to the connection pool
 func (r RowsResult) Close() error {
 	return r.Rows.Close()
@@ -102,10 +194,139 @@ func (r *RowsResult) Scan(dest interface{}) error {
 	if r.Err() != nil {
 		return r.Err()
 	}
-	err := ScanResult(r.Rows, dest)
+	err := getScanner(r.scanner).scanResult(r.Rows, dest)
+	return err
+}
+
+// ScanRow scans the current row to dest, call it after Next, e.g.
+//
+//	rs := db.QueryContext(ctx, "select * from users")
+//	defer rs.Close()
+//	for rs.Next() {
+//		var user User
+//		err := rs.ScanRow(&user)
+//	}
+//	err := rs.Err()
+//
+// the column mapping is computed once for all the rows
+func (r *RowsResult) ScanRow(dest interface{}) error {
+
+	if r.LastError != nil {
+		return r.LastError
+	}
+
+	s := getScanner(r.scanner)
+	if err := s.context().Err(); err != nil {
+		return err
+	}
+
+	destValue := reflect.ValueOf(dest)
+	if destValue.Kind() != reflect.Ptr || destValue.IsNil() {
+		return fmt.Errorf("ploto: ScanRow dest must be a non-nil pointer, got %T", dest)
+	}
+
+	itemType := destValue.Type().Elem()
+	row := r.row
+	r.row++
+	if itemType == mapType || s.isScalar(itemType) {
+		return s.scan(r.Rows, dest, row)
+	}
+
+	if r.planType != itemType {
+		columns, err := r.Columns()
+		if err != nil {
+			return err
+		}
+		plan, err := s.newScanPlan(itemType, columns)
+		if err != nil {
+			return err
+		}
+		r.plan, r.planType = plan, itemType
+	}
+	return s.scanStruct(r.Rows, destValue.Elem(), r.plan, row)
+}
+
+// ErrStopEach returned by the Each callback to stop the iteration without error
+var ErrStopEach = errors.New("ploto: stop each")
+
+// Each scans the rows one by one without buffering the whole result and calls fn with every row,
+// fn must be a func(*T) error. Each stops at the first error returned by fn, ErrStopEach stops without error.
+// The rows are closed when Each returns, the iteration stops when the context of the query is done.
+func (r *RowsResult) Each(fn interface{}) error {
+
+	if r.LastError != nil {
+		return r.LastError
+	}
+	defer r.Rows.Close()
+
+	fnValue := reflect.ValueOf(fn)
+	if !fnValue.IsValid() || fnValue.Kind() != reflect.Func || fnValue.IsNil() {
+		return fmt.Errorf("ploto: Each fn must be a func(*T) error, got %T", fn)
+	}
+	fnType := fnValue.Type()
+	if fnType.NumIn() != 1 || fnType.In(0).Kind() != reflect.Ptr || fnType.NumOut() != 1 || fnType.Out(0) != errorType {
+		return fmt.Errorf("ploto: Each fn must be a func(*T) error, got %T", fn)
+	}
+	itemType := fnType.In(0).Elem()
+
+	for r.Next() {
+		item := reflect.New(itemType)
+		if err := r.ScanRow(item.Interface()); err != nil {
+			return err
+		}
+
+		out := fnValue.Call([]reflect.Value{item})
+		if err, _ := out[0].Interface().(error); err != nil {
+			if err == ErrStopEach {
+				return nil
+			}
+			return err
+		}
+	}
+
+	return r.Err()
+}
+
+// ScanMulti scans the successive result sets to dests, e.g. the result sets of a stored procedure
+//
+//	err := db.Query("exec GetOrder ?", id).ScanMulti(&orders, &lines, &totals)
+func (r *RowsResult) ScanMulti(dests ...interface{}) error {
+
+	if r.LastError != nil {
+		return r.LastError
+	}
+
+	if r.Err() != nil {
+		return r.Err()
+	}
+	err := getScanner(r.scanner).scanMulti(r.Rows, dests...)
+	return err
+}
+
+// ScanMap scans all the rows to the map dest points to, keyed by the keyColumn,
+// dest can be *map[K]V, *map[K]*V, *map[K][]V or *map[K][]*V, e.g. map[int64]User
+func (r *RowsResult) ScanMap(dest interface{}, keyColumn string) error {
+
+	if r.LastError != nil {
+		return r.LastError
+	}
+
+	if r.Err() != nil {
+		return r.Err()
+	}
+	err := getScanner(r.scanner).scanMap(r.Rows, dest, keyColumn)
 	return err
 }
 
+// Strict enables the strict mode of Scan, Scan returns *StrictScanError when a column has no
+// matching struct field or a field tagged required (db:"name,required") is not populated by any column
+func (r *RowsResult) Strict() *RowsResult {
+	s := *getScanner(r.scanner)
+	s.strict = true
+	r.scanner = &s
+	return r
+}
+
 //Raw
 func (r *RowsResult) Raw() (*sql.Rows, error) {
 	return r.Rows, r.LastError
@@ -116,6 +337,14 @@ func (r *RowResult) Err() error {
 	return r.LastError
 }
 
+// Strict enables the strict mode of Scan, see RowsResult.Strict
+func (r *RowResult) Strict() *RowResult {
+	s := *getScanner(r.scanner)
+	s.strict = true
+	r.scanner = &s
+	return r
+}
+
 //Scan RowResult's scan
 func (r *RowResult) Scan(dest interface{}) error {
 
@@ -139,7 +368,7 @@ func (r *RowResult) Scan(dest interface{}) error {
 		return sql.ErrNoRows
 	}
 
-	err := Scan(r.rows, dest)
+	err := getScanner(r.scanner).scan(r.rows, dest, 0)
 
 	if err != nil {
 		return err
@@ -294,3 +294,223 @@ func TestBeginTx(t *testing.T) {
 	t.Logf("Begin:%+v", result)
 
 }
+
+func TestQueryStrict(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+
+	type StrictUsers struct {
+		Id    int64  `db:"id,required"`
+		Name  string `db:"name,required"`
+		Email string `db:"email"`
+	}
+
+	dataRows := sqlmock.NewRows([]string{"id", "nmae"}).
+		AddRow(1, "1111")
+	mock.ExpectQuery("SELECT (.+) FROM users").WillReturnRows(dataRows)
+
+	db := &DB{DB: mockDB}
+
+	var users []StrictUsers
+	err = db.Query("SELECT id,nmae FROM users").Strict().Scan(&users)
+
+	scanErr, ok := err.(*StrictScanError)
+	if !ok {
+		t.Fatalf("should return StrictScanError: %+v", err)
+	}
+	if len(scanErr.UnmappedColumns) != 1 || scanErr.UnmappedColumns[0] != "nmae" {
+		t.Fatalf("unmapped columns error %+v", scanErr)
+	}
+	if len(scanErr.MissingFields) != 1 || scanErr.MissingFields[0] != "Name" {
+		t.Fatalf("missing fields error %+v", scanErr)
+	}
+	t.Logf("strict error: %s", err)
+
+	dataRows = sqlmock.NewRows([]string{"id", "name"}).
+		AddRow(1, "1111")
+	mock.ExpectQuery("SELECT (.+) FROM users").WillReturnRows(dataRows)
+
+	var user StrictUsers
+	err = db.QueryRow("SELECT id,name FROM users").Strict().Scan(&user)
+	if err != nil || user.Name != "1111" {
+		t.Fatalf("strict scan error %+v %+v", err, user)
+	}
+}
+
+func TestQueryScanMap(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+
+	db := &DB{DB: mockDB}
+
+	newRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "name", "created_time", "updated_time"}).
+			AddRow(1, "1111", "2021-10-01 00:00:00", "2021-10-01 00:00:00").
+			AddRow(2, "2222", "2021-10-01 00:00:00", "2021-10-01 00:00:00").
+			AddRow(3, "2222", "2021-10-02 00:00:00", "2021-10-01 00:00:00")
+	}
+
+	mock.ExpectQuery("SELECT (.+) FROM users").WillReturnRows(newRows())
+	var users map[int64]Users
+	err = db.Query("SELECT id,name,created_time,updated_time FROM users").ScanMap(&users, "id")
+	if err != nil || len(users) != 3 || users[2].Name != "2222" {
+		t.Fatalf("ScanMap map[int64]Users error %+v %+v", err, users)
+	}
+
+	mock.ExpectQuery("SELECT (.+) FROM users").WillReturnRows(newRows())
+	var ptrUsers map[int]*Users
+	err = db.Query("SELECT id,name,created_time,updated_time FROM users").ScanMap(&ptrUsers, "id")
+	if err != nil || len(ptrUsers) != 3 || ptrUsers[3].Id != 3 {
+		t.Fatalf("ScanMap map[int]*Users error %+v %+v", err, ptrUsers)
+	}
+
+	mock.ExpectQuery("SELECT (.+) FROM users").WillReturnRows(newRows())
+	var groups map[string][]Users
+	err = db.Query("SELECT id,name,created_time,updated_time FROM users").ScanMap(&groups, "name")
+	if err != nil || len(groups) != 2 || len(groups["2222"]) != 2 {
+		t.Fatalf("ScanMap map[string][]Users error %+v %+v", err, groups)
+	}
+
+	mock.ExpectQuery("SELECT id,name FROM users").WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "a").AddRow(2, "b"))
+	var names map[int64]string
+	err = db.Query("SELECT id,name FROM users").ScanMap(&names, "id")
+	if err != nil || len(names) != 2 || names[2] != "b" {
+		t.Fatalf("ScanMap map[int64]string error %+v %+v", err, names)
+	}
+
+	mock.ExpectQuery("SELECT id,name FROM users").WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "a"))
+	err = db.Query("SELECT id,name FROM users").ScanMap(&names, "uid")
+	if err == nil {
+		t.Fatalf("ScanMap should return error with unknown key column")
+	}
+	type KeyUsers struct {
+		Id   int64  `db:"id"`
+		Name string `db:"name"`
+	}
+	mock.ExpectQuery("SELECT id,name FROM users").WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(65, "a"))
+	var byInt map[int]KeyUsers
+	err = db.Query("SELECT id,name FROM users").ScanMap(&byInt, "id")
+	if err != nil || byInt[65].Name != "a" {
+		t.Fatalf("ScanMap map[int]KeyUsers error %+v %+v", err, byInt)
+	}
+
+	mock.ExpectQuery("SELECT id,name FROM users").WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(65, "a"))
+	var byString map[string]KeyUsers
+	err = db.Query("SELECT id,name FROM users").ScanMap(&byString, "id")
+	if err == nil {
+		t.Fatalf("ScanMap int key into map[string]KeyUsers should be error, got %+v", byString)
+	}
+
+	mock.ExpectQuery("SELECT score,name FROM users").WillReturnRows(sqlmock.NewRows([]string{"score", "name"}).AddRow(1.5, "a"))
+	var byScore map[int]map[string]interface{}
+	err = db.Query("SELECT score,name FROM users").ScanMap(&byScore, "score")
+	if err == nil {
+		t.Fatalf("ScanMap float key into map[int] should be error, got %+v", byScore)
+	}
+}
+
+func TestQueryEach(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+
+	db := &DB{DB: mockDB}
+
+	newRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "name", "created_time", "updated_time"}).
+			AddRow(1, "1111", "2021-10-01 00:00:00", "2021-10-01 00:00:00").
+			AddRow(2, "2222", "2021-10-01 00:00:00", "2021-10-01 00:00:00").
+			AddRow(3, "3333", "2021-10-01 00:00:00", "2021-10-01 00:00:00")
+	}
+
+	mock.ExpectQuery("SELECT (.+) FROM users").WillReturnRows(newRows()).RowsWillBeClosed()
+	var ids []int64
+	err = db.Query("SELECT id,name,created_time,updated_time FROM users").Each(func(user *Users) error {
+		ids = append(ids, user.Id)
+		if user.Id == 2 {
+			return ErrStopEach
+		}
+		return nil
+	})
+	if err != nil || len(ids) != 2 {
+		t.Fatalf("Each error %+v %+v", err, ids)
+	}
+
+	mock.ExpectQuery("SELECT (.+) FROM users").WillReturnRows(newRows())
+	rs := db.Query("SELECT id,name,created_time,updated_time FROM users")
+	var names []string
+	for rs.Next() {
+		var user Users
+		if err := rs.ScanRow(&user); err != nil {
+			t.Fatalf("ScanRow error %+v", err)
+		}
+		names = append(names, user.Name)
+	}
+	rs.Close()
+	if rs.Err() != nil || len(names) != 3 || names[2] != "3333" {
+		t.Fatalf("ScanRow error %+v %+v", rs.Err(), names)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	mock.ExpectQuery("SELECT (.+) FROM users").WillReturnRows(newRows())
+	count := 0
+	err = db.QueryContext(ctx, "SELECT id,name,created_time,updated_time FROM users").Each(func(user *Users) error {
+		count++
+		cancel()
+		return nil
+	})
+	if err != context.Canceled || count != 1 {
+		t.Fatalf("Each should stop when the context is canceled %+v %d", err, count)
+	}
+
+	mock.ExpectQuery("SELECT (.+) FROM users").WillReturnRows(newRows())
+	if err = db.Query("SELECT id,name,created_time,updated_time FROM users").Each(nil); err == nil {
+		t.Fatalf("Each(nil) should return error")
+	}
+	mock.ExpectQuery("SELECT (.+) FROM users").WillReturnRows(newRows())
+	var nilFn func(user *Users) error
+	if err = db.Query("SELECT id,name,created_time,updated_time FROM users").Each(nilFn); err == nil {
+		t.Fatalf("Each with nil func should return error")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations error %+v", err)
+	}
+}
+
+func TestQueryScanMulti(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+
+	db := &DB{DB: mockDB}
+
+	orders := sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "order1").AddRow(2, "order2")
+	lines := sqlmock.NewRows([]string{"id", "name"}).AddRow(10, "line10")
+	totals := sqlmock.NewRows([]string{"total"}).AddRow(3)
+	mock.ExpectQuery("exec GetOrders").WillReturnRows(orders, lines, totals)
+
+	var orderRows []Users
+	var lineRow Users
+	var total int64
+	err = db.Query("exec GetOrders").ScanMulti(&orderRows, &lineRow, &total)
+	if err != nil || len(orderRows) != 2 || lineRow.Id != 10 || total != 3 {
+		t.Fatalf("ScanMulti error %+v %+v %+v %d", err, orderRows, lineRow, total)
+	}
+
+	mock.ExpectQuery("exec GetOrders").WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "order1"))
+	err = db.Query("exec GetOrders").ScanMulti(&orderRows, &total)
+	if err == nil {
+		t.Fatalf("ScanMulti should return error with missing result sets")
+	}
+}
@@ -0,0 +1,54 @@
+package ploto
+
+import (
+	"context"
+	"fmt"
+)
+
+// Queryer is implemented by *DB and *Tx
+type Queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) *RowsResult
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *RowResult
+}
+
+// QueryAll executes the query and scans all the rows to []T,
+// T can be any type supported by RowsResult.Scan, e.g. struct, map[string]interface{} or int64
+func QueryAll[T any](ctx context.Context, q Queryer, query string, args ...interface{}) ([]T, error) {
+	var items []T
+	err := q.QueryContext(ctx, query, args...).Scan(&items)
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// QueryOne executes the query and scans the first row to T,
+// returns sql.ErrNoRows if the query selects no rows
+func QueryOne[T any](ctx context.Context, q Queryer, query string, args ...interface{}) (T, error) {
+	var item T
+	err := q.QueryRowContext(ctx, query, args...).Scan(&item)
+	return item, err
+}
+
+// QueryValue executes the query and scans the single column of the first row to T, e.g. count(1),
+// returns sql.ErrNoRows if the query selects no rows
+func QueryValue[T any](ctx context.Context, q Queryer, query string, args ...interface{}) (T, error) {
+	var value T
+	row := q.QueryRowContext(ctx, query, args...)
+	if row.LastError != nil {
+		return value, row.LastError
+	}
+
+	columns, err := row.rows.Columns()
+	if err != nil {
+		row.rows.Close()
+		return value, err
+	}
+	if len(columns) != 1 {
+		row.rows.Close()
+		return value, fmt.Errorf("ploto: QueryValue expects 1 column, got %d", len(columns))
+	}
+
+	err = row.Scan(&value)
+	return value, err
+}
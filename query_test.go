@@ -0,0 +1,63 @@
+package ploto
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestGenericQuery(t *testing.T) {
+	ctx := context.Background()
+
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+
+	db := &DB{DB: mockDB}
+
+	dataRows := sqlmock.NewRows([]string{"id", "name", "created_time", "updated_time"}).
+		AddRow(1, "1111", "2021-10-01 00:00:00", "2021-10-01 00:00:00").
+		AddRow(2, "2222", "2021-10-01 00:00:00", "2021-10-01 00:00:00")
+	mock.ExpectQuery("SELECT (.+) FROM users WHERE id<?").WithArgs(3).WillReturnRows(dataRows)
+
+	users, err := QueryAll[Users](ctx, db, "SELECT id,name,created_time,updated_time FROM users WHERE id<?", 3)
+	if err != nil || len(users) != 2 || users[1].Name != "2222" {
+		t.Fatalf("QueryAll error %+v %+v", err, users)
+	}
+
+	mock.ExpectBegin()
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Begin with error %+v", err)
+	}
+
+	dataRows = sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "1111")
+	mock.ExpectQuery("SELECT (.+) FROM users WHERE id=?").WithArgs(1).WillReturnRows(dataRows)
+
+	user, err := QueryOne[*Users](ctx, tx, "SELECT id,name FROM users WHERE id=?", 1)
+	if err != nil || user == nil || user.Id != 1 {
+		t.Fatalf("QueryOne error %+v %+v", err, user)
+	}
+
+	mock.ExpectQuery("SELECT (.+) FROM users WHERE id=?").WithArgs(2).WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+	_, err = QueryOne[Users](ctx, tx, "SELECT id,name FROM users WHERE id=?", 2)
+	if err != sql.ErrNoRows {
+		t.Fatalf("QueryOne should return ErrNoRows %+v", err)
+	}
+
+	mock.ExpectQuery("SELECT count").WillReturnRows(sqlmock.NewRows([]string{"cnt"}).AddRow(5))
+	count, err := QueryValue[int64](ctx, tx, "SELECT count(1) as cnt FROM users")
+	if err != nil || count != 5 {
+		t.Fatalf("QueryValue error %+v %+v", err, count)
+	}
+
+	mock.ExpectQuery("SELECT id,name").WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "1111"))
+	_, err = QueryValue[int64](ctx, tx, "SELECT id,name FROM users")
+	if err == nil {
+		t.Fatalf("QueryValue should return error with 2 columns")
+	}
+}
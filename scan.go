@@ -1,21 +1,26 @@
 package ploto
 
 import (
+	"context"
 	"database/sql"
 	"database/sql/driver"
+	"errors"
 	"fmt"
 	"reflect"
+	"strings"
+	"sync"
 	"time"
-
-	"golang.org/x/text/cases"
-	"golang.org/x/text/language"
 )
 
 // valuesToMap  sql values转化为map
 func valuesToMap(mapValue map[string]interface{}, values []interface{}, columns []string) {
 	for idx, column := range columns {
 
-		reflectValue := reflect.ValueOf(values[idx])
+		value := values[idx]
+		if fs, ok := value.(*fieldScanner); ok {
+			value = fs.dest
+		}
+		reflectValue := reflect.ValueOf(value)
 		if !reflectValue.IsValid() {
 			mapValue[column] = nil
 			continue
@@ -33,52 +38,783 @@ func valuesToMap(mapValue map[string]interface{}, values []interface{}, columns
 	}
 }
 
+// structField the cached metadata of a struct field
+type structField struct {
+	name  string
+	path  string
+	index []int
+	field reflect.StructField
+	// required the field must be populated by a column in strict mode
+	required bool
+	// json the column is a JSON document decoded into the field, db:"name,json"
+	json bool
+	// nullZero the field receives the zero value for NULL, db:"name,nullzero"
+	nullZero bool
+	// nested the field of a named struct field, matched by the prefixed columns only
+	nested bool
+	// lazy the field is reached through a nested pointer field, which is allocated
+	// only when any of its columns is not NULL
+	lazy bool
+	// alloc the field is reached through an embedded pointer struct, which is allocated on demand
+	alloc bool
+	// pk the primary key column, db:"id,pk"
+	pk bool
+	// autoIncr the column is generated by the database, skipped by Insert and set to the generated id, db:"id,autoincr"
+	autoIncr bool
+	// omitEmpty the zero value is not written by Insert, db:"name,omitempty"
+	omitEmpty bool
+	// version the optimistic locking version, checked and incremented by Update, db:"version,version"
+	version bool
+	// composite the tagged struct field whose fields receive the prefixed columns,
+	// it is mapped to the column itself only if its type has a registered converter
+	composite bool
+}
+
+// structInfo the cached fields of a struct type
+type structInfo struct {
+	// tagged fields keyed by db tag
+	tagged map[string]*structField
+	// fields all the exported fields, including the fields of embedded structs
+	fields []*structField
+	// columns the tagged fields written by the write helpers, in the declaration order
+	columns []*structField
+}
+
+// columnsKey the cache key of the column mapping for a struct type
+type columnsKey struct {
+	typ     reflect.Type
+	mapper  NameMapper
+	columns string
+}
+
+var (
+	// structInfoCache map[reflect.Type]*structInfo
+	structInfoCache sync.Map
+	// columnFieldsCache map[columnsKey][]*structField
+	columnFieldsCache sync.Map
+)
+
+// tagOptions the options of the db tag after the column name, e.g. db:"name,required"
+type tagOptions string
+
+// parseTag splits the db tag into the column name and the options
+func parseTag(tag string) (string, tagOptions) {
+	if idx := strings.Index(tag, ","); idx != -1 {
+		return tag[:idx], tagOptions(tag[idx+1:])
+	}
+	return tag, tagOptions("")
+}
+
+// Contains reports whether the options contains the option name
+func (o tagOptions) Contains(name string) bool {
+	s := string(o)
+	for s != "" {
+		var opt string
+		if idx := strings.Index(s, ","); idx >= 0 {
+			opt, s = s[:idx], s[idx+1:]
+		} else {
+			opt, s = s, ""
+		}
+		if opt == name {
+			return true
+		}
+	}
+	return false
+}
+
+// getStructInfo returns the cached field metadata of the struct type
+func getStructInfo(typ reflect.Type) *structInfo {
+	if v, ok := structInfoCache.Load(typ); ok {
+		return v.(*structInfo)
+	}
+
+	info := &structInfo{tagged: make(map[string]*structField)}
+	initStructFieldTags(typ, &fieldScope{types: []reflect.Type{typ}}, info)
+	for _, f := range info.fields {
+		if f.name != "" && f.name != "-" && !f.nested && info.tagged[f.name] == f {
+			info.columns = append(info.columns, f)
+		}
+	}
+
+	v, _ := structInfoCache.LoadOrStore(typ, info)
+	return v.(*structInfo)
+}
+
+// fieldScope the position of the struct being walked inside the scanned type
+type fieldScope struct {
+	index []int
+	path  string
+	// dotPrefix, underscorePrefix the column prefixes of the nested struct, e.g. author. and author_
+	dotPrefix        string
+	underscorePrefix string
+	// nested the struct is a named struct field
+	nested bool
+	// lazy the struct is reached through a nested pointer field
+	lazy bool
+	// alloc the struct is reached through an embedded pointer field
+	alloc bool
+	// types the struct types being walked, to stop the recursive types
+	types []reflect.Type
+}
+
+// child returns the scope of the struct field
+func (scope *fieldScope) child(field reflect.StructField, i int) *fieldScope {
+	child := *scope
+	child.index = append(append(make([]int, 0, len(scope.index)+1), scope.index...), i)
+	child.path = scope.path + field.Name + "."
+	child.types = append(append(make([]reflect.Type, 0, len(scope.types)+1), scope.types...), indirectType(field.Type))
+	return &child
+}
+
+// walking reports whether the struct type is being walked
+func (scope *fieldScope) walking(typ reflect.Type) bool {
+	for _, t := range scope.types {
+		if t == typ {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	timeType    = reflect.TypeOf(time.Time{})
+	mapType     = reflect.TypeOf(map[string]interface{}(nil))
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// indirectType returns the element type of the pointer type
+func indirectType(typ reflect.Type) reflect.Type {
+	if typ.Kind() == reflect.Ptr {
+		return typ.Elem()
+	}
+	return typ
+}
+
+// isNestedStruct reports whether the tagged field is a named struct whose fields receive the prefixed columns
+func isNestedStruct(field reflect.StructField, options tagOptions) bool {
+	if options.Contains("json") {
+		return false
+	}
+	typ := indirectType(field.Type)
+	if typ.Kind() != reflect.Struct || typ == timeType {
+		return false
+	}
+	return !reflect.PtrTo(typ).Implements(scannerType)
+}
+
 // initStructFieldTags
-func initStructFieldTags(item reflect.Value, fieldTagMap *map[string]reflect.Value) {
+func initStructFieldTags(typ reflect.Type, scope *fieldScope, info *structInfo) {
 
-	typ := item.Type() //value’s type
-	for i := 0; i < item.NumField(); i++ {
+	for i := 0; i < typ.NumField(); i++ {
 		field := typ.Field(i)
+
 		// support anonymous field, struct embedding
 		if field.Anonymous {
-			initStructFieldTags(item.Field(i), fieldTagMap)
+			if field.Type.Kind() == reflect.Struct && !scope.walking(field.Type) {
+				initStructFieldTags(field.Type, scope.child(field, i), info)
+			}
+			// embedded pointer struct, allocated on demand
+			if field.Type.Kind() == reflect.Ptr && field.Type.Elem().Kind() == reflect.Struct &&
+				field.PkgPath == "" && !scope.walking(field.Type.Elem()) {
+				child := scope.child(field, i)
+				child.alloc = true
+				initStructFieldTags(field.Type.Elem(), child, info)
+			}
 			continue
 		}
-		tag, ok := typ.Field(i).Tag.Lookup("db")
+		if field.PkgPath != "" {
+			// unexported
+			continue
+		}
+
+		name, options := parseTag(field.Tag.Get("db"))
+		composite := name != "" && isNestedStruct(field, options)
+
+		index := append(append(make([]int, 0, len(scope.index)+1), scope.index...), i)
+		f := &structField{
+			name:      name,
+			path:      scope.path + field.Name,
+			index:     index,
+			field:     field,
+			required:  options.Contains("required"),
+			json:      options.Contains("json"),
+			nullZero:  options.Contains("nullzero"),
+			nested:    scope.nested,
+			lazy:      scope.lazy,
+			alloc:     scope.alloc,
+			pk:        options.Contains("pk"),
+			autoIncr:  options.Contains("autoincr"),
+			omitEmpty: options.Contains("omitempty"),
+			version:   options.Contains("version"),
+			composite: composite,
+		}
+		info.fields = append(info.fields, f)
+
+		if composite {
+			// named struct field, e.g. Author User `db:"author"` receives author.id or author_id,
+			// or the author column itself if its type has a registered converter
+			elemType := indirectType(field.Type)
+			if !scope.walking(elemType) {
+				child := scope.child(field, i)
+				child.dotPrefix = scope.dotPrefix + name + "."
+				child.underscorePrefix = scope.underscorePrefix + name + "_"
+				child.nested = true
+				child.lazy = scope.lazy || field.Type.Kind() == reflect.Ptr
+				initStructFieldTags(elemType, child, info)
+			}
+		}
 
-		if ok && tag != "" {
-			(*fieldTagMap)[tag] = item.Field(i)
+		if name == "" {
+			continue
+		}
+		if !scope.nested {
+			info.tagged[name] = f
+			continue
+		}
+		for _, key := range []string{scope.dotPrefix + name, scope.underscorePrefix + name} {
+			if _, ok := info.tagged[key]; !ok {
+				info.tagged[key] = f
+			}
 		}
 	}
 }
 
-// initStructValues
-func initStructValues(item reflect.Value, columns []string, values []interface{}) {
-	fieldTagMap := make(map[string]reflect.Value, len(columns))
-	initStructFieldTags(item, &fieldTagMap)
+// mapperFieldNames returns the fields keyed by the mapped field names,
+// the shallower field wins, fields at the same depth with the same name are ambiguous.
+func mapperFieldNames(info *structInfo, mapper NameMapper) map[string]*structField {
+	names := make(map[string]*structField, len(info.fields))
+	depths := make(map[string]int, len(info.fields))
+
+	for _, f := range info.fields {
+		if f.nested {
+			continue
+		}
+		name := mapper.FieldName(f.field)
+		depth, ok := depths[name]
+		if !ok || len(f.index) < depth {
+			names[name] = f
+			depths[name] = len(f.index)
+		} else if len(f.index) == depth {
+			names[name] = nil
+		}
+	}
+	return names
+}
+
+// columnFields returns the field of every column, nil for the unmapped column
+// the mappers which can not be a map key, e.g. a struct holding a func, are not cached
+func columnFields(typ reflect.Type, mapper NameMapper, columns []string) []*structField {
+	cacheable := isHashable(mapper)
+	key := columnsKey{typ: typ, mapper: mapper, columns: strings.Join(columns, "\x00")}
+	if cacheable {
+		if v, ok := columnFieldsCache.Load(key); ok {
+			return v.([]*structField)
+		}
+	}
 
+	info := getStructInfo(typ)
+	names := mapperFieldNames(info, mapper)
+	fields := make([]*structField, len(columns))
 	for i, column := range columns {
-		var fieldValue reflect.Value
-		if v, ok := fieldTagMap[column]; ok {
-			fieldValue = v
-		} else {
-			fieldValue = item.FieldByName(cases.Title(language.Und, cases.NoLower).String(column))
+		if f, ok := info.tagged[column]; ok {
+			fields[i] = f
+			continue
 		}
 
-		if !fieldValue.CanSet() {
+		fields[i] = names[mapper.ColumnName(column)]
+	}
+
+	if !cacheable {
+		return fields
+	}
+	v, _ := columnFieldsCache.LoadOrStore(key, fields)
+	return v.([]*structField)
+}
+
+// isHashable reports whether the value can be a map key, the comparable structs and arrays
+// holding the interfaces are compared to find the dynamic values that are not comparable
+func isHashable(v interface{}) (hashable bool) {
+	typ := reflect.TypeOf(v)
+	if typ == nil {
+		return true
+	}
+	if !typ.Comparable() {
+		return false
+	}
+	if !holdsInterface(typ) {
+		return true
+	}
+
+	defer func() {
+		if recover() != nil {
+			hashable = false
+		}
+	}()
+	return v == v
+}
+
+// holdsInterface reports whether the comparable type holds any interface value
+func holdsInterface(typ reflect.Type) bool {
+	switch typ.Kind() {
+	case reflect.Interface:
+		return true
+	case reflect.Array:
+		return holdsInterface(typ.Elem())
+	case reflect.Struct:
+		for i := 0; i < typ.NumField(); i++ {
+			if holdsInterface(typ.Field(i).Type) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// StrictScanError returned by the strict mode scan when the columns and the struct fields do not match
+type StrictScanError struct {
+	// Type the destination struct type
+	Type reflect.Type
+	// UnmappedColumns the columns without matching field
+	UnmappedColumns []string
+	// MissingFields the fields tagged required that no column populated
+	MissingFields []string
+}
+
+func (e *StrictScanError) Error() string {
+	var msg []string
+	if len(e.UnmappedColumns) > 0 {
+		msg = append(msg, fmt.Sprintf("unmapped columns: %s", strings.Join(e.UnmappedColumns, ", ")))
+	}
+	if len(e.MissingFields) > 0 {
+		msg = append(msg, fmt.Sprintf("required fields not populated: %s", strings.Join(e.MissingFields, ", ")))
+	}
+	return fmt.Sprintf("ploto: strict scan into %s: %s", e.Type, strings.Join(msg, "; "))
+}
+
+// ScanError the column conversion error of the scan
+type ScanError struct {
+	// Column the name of the column
+	Column string
+	// ColumnIndex the index of the column
+	ColumnIndex int
+	// Type the destination type
+	Type reflect.Type
+	// Field the path of the struct field, empty for the scalar destination
+	Field string
+	// Row the index of the row in the result set, -1 if unknown
+	Row int
+	// Err the conversion error
+	Err error
+}
+
+func (e *ScanError) Error() string {
+	dest := fmt.Sprint(e.Type)
+	if e.Field != "" {
+		dest += "." + e.Field
+	}
+	return fmt.Sprintf("ploto: scan column %q (index %d) into %s at row %d: %v", e.Column, e.ColumnIndex, dest, e.Row, e.Err)
+}
+
+// Unwrap returns the conversion error
+func (e *ScanError) Unwrap() error {
+	return e.Err
+}
+
+// newScanError sets the destination of the *ScanError returned by scanColumns,
+// the other errors are returned as is
+func newScanError(err error, typ reflect.Type, fields []*structField, row int) error {
+	scanErr, ok := err.(*ScanError)
+	if !ok {
+		return err
+	}
+
+	scanErr.Type = typ
+	scanErr.Row = row
+	if scanErr.ColumnIndex >= 0 && scanErr.ColumnIndex < len(fields) && fields[scanErr.ColumnIndex] != nil {
+		scanErr.Field = fields[scanErr.ColumnIndex].path
+	}
+	return scanErr
+}
+
+// scanColumns scans the current row to the values, the conversion error is returned as *ScanError.
+// The failed column is found by scanning the columns one by one into the fresh values of the same types.
+func scanColumns(rows *sql.Rows, values ...interface{}) error {
+	err := rows.Scan(values...)
+	if err == nil {
+		return nil
+	}
+
+	probes := make([]interface{}, len(values))
+	for i := range probes {
+		probes[i] = new(interface{})
+	}
+	if rows.Scan(probes...) != nil {
+		// not a conversion error, e.g. the rows are closed
+		return err
+	}
+
+	for i, value := range values {
+		probes[i] = probeValue(value)
+		if probeErr := rows.Scan(probes...); probeErr != nil {
+			scanErr := &ScanError{ColumnIndex: i, Row: -1, Err: probeErr}
+			if inner := errors.Unwrap(probeErr); inner != nil {
+				scanErr.Err = inner
+			}
+			if columns, colErr := rows.Columns(); colErr == nil && i < len(columns) {
+				scanErr.Column = columns[i]
+			}
+			return scanErr
+		}
+		probes[i] = new(interface{})
+	}
+	return err
+}
+
+// probeValue returns the fresh scan value of the same type, which does not change the scanned item
+func probeValue(value interface{}) interface{} {
+	if fs, ok := value.(*fieldScanner); ok {
+		probe := *fs
+		probe.dest = probeValue(fs.dest)
+		return &probe
+	}
+	typ := reflect.TypeOf(value)
+	if typ == nil || typ.Kind() != reflect.Ptr {
+		return value
+	}
+	return reflect.New(typ.Elem()).Interface()
+}
+
+// checkStrict returns *StrictScanError if any column is unmapped or any required field is not populated
+func checkStrict(typ reflect.Type, columns []string, fields []*structField) error {
+	var scanErr StrictScanError
+	populated := make(map[*structField]bool, len(fields))
+
+	for i, f := range fields {
+		if f == nil {
+			scanErr.UnmappedColumns = append(scanErr.UnmappedColumns, columns[i])
+			continue
+		}
+		populated[f] = true
+	}
+
+	for _, f := range getStructInfo(typ).fields {
+		if f.required && !populated[f] {
+			scanErr.MissingFields = append(scanErr.MissingFields, f.path)
+		}
+	}
+
+	if len(scanErr.UnmappedColumns) == 0 && len(scanErr.MissingFields) == 0 {
+		return nil
+	}
+	scanErr.Type = typ
+	return &scanErr
+}
+
+// scanPlan the mapping of the columns to the struct fields, computed once per scan
+type scanPlan struct {
+	fields []*structField
+	// afterScan the struct implements AfterScanner
+	afterScan bool
+	// snapshot the struct embeds Snapshot
+	snapshot bool
+	// converters the registered converters of the fields, nil if none
+	converters []Converter
+	// nullZero the fields receiving the zero value for NULL, nil if none
+	nullZero []bool
+}
+
+// converter returns the converter of the i-th column
+func (plan *scanPlan) converter(i int) Converter {
+	if plan.converters == nil {
+		return nil
+	}
+	return plan.converters[i]
+}
+
+// isNullZero reports whether the field of the i-th column receives the zero value for NULL
+func (plan *scanPlan) isNullZero(i int) bool {
+	return plan.nullZero != nil && plan.nullZero[i]
+}
+
+// isNullable reports whether NULL can be scanned into the values of typ
+func isNullable(typ reflect.Type) bool {
+	switch typ.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+		return true
+	}
+	return reflect.PtrTo(typ).Implements(scannerType)
+}
+
+// newScanPlan maps the columns to the fields of the struct type
+func (s *scanner) newScanPlan(typ reflect.Type, columns []string) (*scanPlan, error) {
+	fields := s.resolveComposite(columnFields(typ, s.nameMapper(), columns))
+	if s.strict {
+		if err := checkStrict(typ, columns, fields); err != nil {
+			return nil, err
+		}
+	}
+
+	plan := &scanPlan{
+		fields:    fields,
+		afterScan: reflect.PtrTo(typ).Implements(afterScannerType),
+		snapshot:  reflect.PtrTo(typ).Implements(snapshotterType),
+	}
+	for i, f := range fields {
+		if f == nil {
+			continue
+		}
+		converter, ok := lookupFieldConverter(s.converters, f.field.Type)
+		if f.json {
+			converter, ok = jsonConverter{}, true
+		} else if !ok && s.coerce && isCoercible(f.field.Type) {
+			converter, ok = coerceConverter{}, true
+		}
+		if ok {
+			if plan.converters == nil {
+				plan.converters = make([]Converter, len(fields))
+			}
+			plan.converters[i] = converter
+		}
+
+		// NULL is still an error in strict mode
+		if (s.nullZero || f.nullZero) && !s.strict && !isNullable(f.field.Type) {
+			if plan.nullZero == nil {
+				plan.nullZero = make([]bool, len(fields))
+			}
+			plan.nullZero[i] = true
+		}
+	}
+	return plan, nil
+}
+
+// resolveComposite unmaps the composite fields without a registered converter,
+// the cached fields are copied before changed
+func (s *scanner) resolveComposite(fields []*structField) []*structField {
+	resolved := fields
+	for i, f := range fields {
+		if f == nil || !f.composite {
+			continue
+		}
+		if _, ok := lookupFieldConverter(s.converters, f.field.Type); ok {
+			continue
+		}
+		if &resolved[0] == &fields[0] {
+			resolved = append([]*structField(nil), fields...)
+		}
+		resolved[i] = nil
+	}
+	return resolved
+}
+
+// fieldValue returns the field of the item, allocating the embedded pointer structs
+func fieldValue(item reflect.Value, f *structField) reflect.Value {
+	if f.alloc {
+		return fieldByIndexAlloc(item, f.index)
+	}
+	return item.FieldByIndex(f.index)
+}
+
+// initStructValues
+func initStructValues(item reflect.Value, plan *scanPlan, values []interface{}) {
+
+	for i, f := range plan.fields {
+		if f == nil {
 			values[i] = new(interface{})
+			continue
+		}
+
+		if converter := plan.converter(i); converter != nil {
+			var dest reflect.Value
+			if f.lazy {
+				dest = reflect.New(f.field.Type)
+			} else {
+				dest = fieldValue(item, f).Addr()
+			}
+			values[i] = &fieldScanner{converter: converter, dest: dest.Interface(), nullZero: plan.isNullZero(i)}
+			continue
+		}
+
+		if f.lazy || plan.isNullZero(i) {
+			// **T, nil when the column is NULL
+			values[i] = reflect.New(reflect.PtrTo(f.field.Type)).Interface()
+			continue
+		}
+
+		//*Value
+		values[i] = fieldValue(item, f).Addr().Interface()
+	}
+
+}
+
+// assignHeldValues sets the values held by initStructValues for the lazy and the nullzero fields,
+// allocating the nested pointer structs
+func assignHeldValues(item reflect.Value, plan *scanPlan, values []interface{}) {
+	for i, f := range plan.fields {
+		if f == nil || !(f.lazy || plan.isNullZero(i)) {
+			continue
+		}
+
+		var value reflect.Value
+		if fs, ok := values[i].(*fieldScanner); ok {
+			if !f.lazy || fs.null {
+				// scanned into the field directly
+				continue
+			}
+			value = reflect.ValueOf(fs.dest).Elem()
 		} else {
-			//*Value
-			values[i] = fieldValue.Addr().Interface()
+			ptr := reflect.ValueOf(values[i]).Elem()
+			if ptr.IsNil() {
+				if !f.lazy {
+					// nullzero
+					fieldValue(item, f).Set(reflect.Zero(f.field.Type))
+				}
+				continue
+			}
+			value = ptr.Elem()
 		}
+		fieldByIndexAlloc(item, f.index).Set(value)
+	}
+}
+
+// fieldByIndexAlloc returns the nested field by index, allocating the nil pointer structs along the path
+func fieldByIndexAlloc(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+// fieldByIndexNoAlloc returns the nested field by index, the zero Value if a pointer struct along the path is nil
+func fieldByIndexNoAlloc(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+// scanStruct scans the current row to the struct item
+func (s *scanner) scanStruct(rows *sql.Rows, item reflect.Value, plan *scanPlan, row int) error {
+	values := make([]interface{}, len(plan.fields))
+	initStructValues(item, plan, values)
+
+	return s.scanStructValues(rows, item, plan, values, row)
+}
+
+// scanStructValues scans the current row to the values initialized by initStructValues
+func (s *scanner) scanStructValues(rows *sql.Rows, item reflect.Value, plan *scanPlan, values []interface{}, row int) error {
+	if err := scanColumns(rows, values...); err != nil {
+		return newScanError(err, item.Type(), plan.fields, row)
+	}
+
+	assignHeldValues(item, plan, values)
+
+	if plan.snapshot {
+		takeSnapshot(item, s.converters)
+	}
+	if plan.afterScan {
+		return callAfterScan(s.context(), item)
 	}
+	return nil
+}
+
+// scanner scans the rows with the options of the DB
+type scanner struct {
+	// ctx the context of the query
+	ctx    context.Context
+	mapper NameMapper
+	// strict reports the unmapped columns and the unpopulated required fields
+	strict bool
+	// converters the converters of the DB, consulted before DefaultConverters
+	converters *ConverterRegistry
+	// coerce converts the mismatched column types into the field types
+	coerce bool
+	// nullZero scans NULL as the zero value of the non-nullable types, except in strict mode
+	nullZero bool
+}
 
+// defaultScanner used by the package level Scan functions
+var defaultScanner = &scanner{mapper: DefaultNameMapper}
+
+// context returns the context of the query, context.Background() if not set
+func (s *scanner) context() context.Context {
+	if s.ctx == nil {
+		return context.Background()
+	}
+	return s.ctx
 }
 
+// nameMapper returns the NameMapper of the scanner
+func (s *scanner) nameMapper() NameMapper {
+	if s.mapper == nil {
+		return DefaultNameMapper
+	}
+	return s.mapper
+}
+
+// isScalar reports whether the value of typ is scanned from a single column,
+// all the types except structs and map[string]interface{}, time.Time, sql.Scanner and the converted types
+func (s *scanner) isScalar(typ reflect.Type) bool {
+	if typ.Kind() != reflect.Struct {
+		return typ != mapType
+	}
+	if typ == timeType || reflect.PtrTo(typ).Implements(scannerType) {
+		return true
+	}
+	_, ok := lookupConverter(s.converters, typ)
+	return ok
+}
+
+// ScanResult scans the rows to dest and closes the rows,
+// dest can be a pointer to slice or a pointer to the type supported by Scan
 func ScanResult(rows *sql.Rows, dest interface{}) error {
+	return defaultScanner.scanResult(rows, dest)
+}
+
+// ScanSlice scans all the rows to the slice dest points to
+func ScanSlice(rows *sql.Rows, dest interface{}) error {
+	return defaultScanner.scanSlice(rows, dest)
+}
+
+// Scan scans the current row to dest
+func Scan(rows *sql.Rows, dest interface{}) error {
+	return defaultScanner.scan(rows, dest, -1)
+}
+
+// ScanMulti scans the successive result sets to dests and closes the rows,
+// e.g. the result sets of a stored procedure. Every dest can be any type supported by ScanResult.
+func ScanMulti(rows *sql.Rows, dests ...interface{}) error {
+	return defaultScanner.scanMulti(rows, dests...)
+}
+
+// ScanMap scans all the rows to the map dest points to, keyed by the keyColumn and closes the rows,
+// dest can be *map[K]V, *map[K]*V, *map[K][]V or *map[K][]*V
+func ScanMap(rows *sql.Rows, dest interface{}, keyColumn string) error {
+	return defaultScanner.scanMap(rows, dest, keyColumn)
+}
+
+func (s *scanner) scanResult(rows *sql.Rows, dest interface{}) error {
 
 	defer rows.Close()
 
+	return s.scanResultSet(rows, dest)
+}
+
+// scanResultSet scans the current result set to dest
+func (s *scanner) scanResultSet(rows *sql.Rows, dest interface{}) error {
+
 	destType := reflect.TypeOf(dest)
 
 	if k := destType.Kind(); k != reflect.Ptr {
@@ -87,15 +823,16 @@ func ScanResult(rows *sql.Rows, dest interface{}) error {
 
 	sliceType := destType.Elem()
 
-	if reflect.Slice == sliceType.Kind() {
+	if reflect.Slice == sliceType.Kind() && sliceType.Elem().Kind() != reflect.Uint8 {
+		// []byte is scanned as a scalar
 		//slice
-		err := ScanSlice(rows, dest)
+		err := s.scanSlice(rows, dest)
 		return err
 
 	} else {
 		//other
 		if rows.Next() {
-			err := Scan(rows, dest)
+			err := s.scan(rows, dest, 0)
 			return err
 		}
 	}
@@ -104,7 +841,28 @@ func ScanResult(rows *sql.Rows, dest interface{}) error {
 
 }
 
-func ScanSlice(rows *sql.Rows, dest interface{}) error {
+// scanMulti scans the successive result sets to dests and closes the rows
+func (s *scanner) scanMulti(rows *sql.Rows, dests ...interface{}) error {
+
+	defer rows.Close()
+
+	for i, dest := range dests {
+		if i > 0 && !rows.NextResultSet() {
+			if err := rows.Err(); err != nil {
+				return err
+			}
+			return fmt.Errorf("ploto: ScanMulti expects %d result sets, got %d", len(dests), i)
+		}
+
+		if err := s.scanResultSet(rows, dest); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+func (s *scanner) scanSlice(rows *sql.Rows, dest interface{}) error {
 
 	//columns
 	columns, _ := rows.Columns()
@@ -122,15 +880,54 @@ func ScanSlice(rows *sql.Rows, dest interface{}) error {
 
 	sliceVal := reflect.Indirect(reflect.ValueOf(dest))
 
-	for rows.Next() {
+	if itemType == mapType || s.isScalar(itemType) {
+		// []int64, []sql.NullString, []map[string]interface{} ...
+		var columnTypes []*sql.ColumnType
+		if itemType == mapType {
+			var err error
+			if columnTypes, err = rows.ColumnTypes(); err != nil {
+				return err
+			}
+		}
 
-		values := make([]interface{}, len(columns))
+		for row := 0; rows.Next(); row++ {
 
-		sliceItem := reflect.New(itemType).Elem()
+			sliceItem := reflect.New(itemType)
+
+			var err error
+			if itemType == mapType {
+				m := make(map[string]interface{}, len(columns))
+				err = s.scanMapRow(rows, &m, columns, columnTypes)
+				sliceItem.Elem().Set(reflect.ValueOf(m))
+			} else if ok, scalarErr := s.scanScalar(rows, sliceItem.Interface()); ok {
+				err = scalarErr
+			} else {
+				err = s.scanValue(rows, sliceItem.Interface(), row)
+			}
 
-		initStructValues(sliceItem, columns, values)
+			if err := destScanError(err, sliceItem.Interface(), row); err != nil {
+				return err
+			}
 
-		err := rows.Scan(values...)
+			if isPtr {
+				sliceVal.Set(reflect.Append(sliceVal, sliceItem))
+			} else {
+				sliceVal.Set(reflect.Append(sliceVal, sliceItem.Elem()))
+			}
+		}
+		return nil
+	}
+
+	plan, err := s.newScanPlan(itemType, columns)
+	if err != nil {
+		return err
+	}
+
+	for row := 0; rows.Next(); row++ {
+
+		sliceItem := reflect.New(itemType).Elem()
+
+		err := s.scanStruct(rows, sliceItem, plan, row)
 
 		if err != nil {
 			return err
@@ -148,55 +945,284 @@ func ScanSlice(rows *sql.Rows, dest interface{}) error {
 
 }
 
-// Scan
-func Scan(rows *sql.Rows, dest interface{}) error {
+// scan scans the current row to dest, row is the index of the row in the result set, -1 if unknown
+func (s *scanner) scan(rows *sql.Rows, dest interface{}, row int) error {
+	return destScanError(s.scanValue(rows, dest, row), dest, row)
+}
+
+// destScanError sets the destination of the *ScanError not returned by the struct scan
+func destScanError(err error, dest interface{}, row int) error {
+	if scanErr, ok := err.(*ScanError); !ok || scanErr.Type != nil {
+		// the other errors, or the struct scan error with its destination
+		return err
+	}
+
+	destType := reflect.TypeOf(dest)
+	if destType != nil && destType.Kind() == reflect.Ptr {
+		destType = destType.Elem()
+	}
+	return newScanError(err, destType, nil, row)
+}
+
+func (s *scanner) scanValue(rows *sql.Rows, dest interface{}, row int) error {
+	if ok, err := s.scanScalar(rows, dest); ok {
+		return err
+	}
+
 	//columns
 	columns, _ := rows.Columns()
-	values := make([]interface{}, len(columns))
-	columnTypes, err := rows.ColumnTypes()
+
+	if m, ok := dest.(*map[string]interface{}); ok {
+		columnTypes, err := rows.ColumnTypes()
+		if err != nil {
+			return err
+		}
+		return s.scanMapRow(rows, m, columns, columnTypes)
+	}
+
+	destValue := reflect.ValueOf(dest).Elem() //destType.Elem()
+	if destValue.Kind() == reflect.Ptr {
+		// **Struct
+		if destValue.IsNil() {
+			destValue.Set(reflect.New(destValue.Type().Elem()))
+		}
+		return s.scan(rows, destValue.Interface(), row)
+	}
+
+	//scan to struct
+	plan, err := s.newScanPlan(destValue.Type(), columns)
 	if err != nil {
 		return err
 	}
 
-	// destType := reflect.TypeOf(dest)
+	return s.scanStruct(rows, destValue, plan, row)
+}
 
-	// if k := destType.Kind(); k != reflect.Ptr {
-	// 	return fmt.Errorf("%s must be a pointer:", k.String())
-	// }
+// scanScalar scans the single column to dest if it is a scalar destination,
+// ok is false for the maps and the structs
+func (s *scanner) scanScalar(rows *sql.Rows, dest interface{}) (ok bool, err error) {
+	destType := reflect.TypeOf(dest)
+	if destType == nil || destType.Kind() != reflect.Ptr {
+		return true, scanColumns(rows, dest)
+	}
 
-	switch dType := dest.(type) {
+	if converter, ok := lookupFieldConverter(s.converters, destType.Elem()); ok {
+		return true, scanColumns(rows, &fieldScanner{converter: converter, dest: dest})
+	}
+	nullZero := s.nullZero && !s.strict && !isNullable(destType.Elem())
+	if s.coerce && isCoercible(destType.Elem()) {
+		return true, scanColumns(rows, &fieldScanner{converter: coerceConverter{}, dest: dest, nullZero: nullZero})
+	}
+	if nullZero && s.isScalar(destType.Elem()) {
+		// **T, nil when the column is NULL
+		holder := reflect.New(destType)
+		if err := scanColumns(rows, holder.Interface()); err != nil {
+			return true, err
+		}
+		destValue := reflect.ValueOf(dest).Elem()
+		if holder.Elem().IsNil() {
+			destValue.Set(reflect.Zero(destValue.Type()))
+		} else {
+			destValue.Set(holder.Elem().Elem())
+		}
+		return true, nil
+	}
 
+	switch dest.(type) {
 	case *int, *int8, *int16, *int32, *int64,
 		*uint, *uint8, *uint16, *uint32, *uint64, *uintptr,
 		*float32, *float64,
 		*bool, *string, *time.Time,
 		*sql.NullInt32, *sql.NullInt64, *sql.NullFloat64,
-		*sql.NullBool, *sql.NullString, *sql.NullTime:
+		*sql.NullBool, *sql.NullString, *sql.NullTime,
+		*[]byte, *sql.RawBytes:
+		return true, scanColumns(rows, dest)
+	}
 
-		err := rows.Scan(dType)
+	elemType := destType.Elem()
+	if elemType.Kind() == reflect.Ptr && !s.isScalar(elemType.Elem()) {
+		// **Struct
+		return false, nil
+	}
+	if s.isScalar(elemType) {
+		// named scalar types, sql.Scanner ...
+		return true, scanColumns(rows, dest)
+	}
+	return false, nil
+}
 
-		return err
-	case *map[string]interface{}:
-		for i := 0; i < len(columns); i++ {
-			//*T
-			if columnTypes[i].ScanType() != nil {
-				values[i] = reflect.New(columnTypes[i].ScanType()).Interface()
-			} else {
-				values[i] = new(interface{})
+// scanMapRow scans the current row to the map, the values are scanned by the scan types of the columns
+func (s *scanner) scanMapRow(rows *sql.Rows, dest *map[string]interface{}, columns []string, columnTypes []*sql.ColumnType) error {
+	values := make([]interface{}, len(columns))
+	for i := 0; i < len(columns); i++ {
+		//*T
+		if scanType := columnTypes[i].ScanType(); scanType != nil {
+			values[i] = reflect.New(scanType).Interface()
+			if converter, ok := lookupConverter(s.converters, scanType); ok {
+				values[i] = &fieldScanner{converter: converter, dest: values[i]}
 			}
+		} else {
+			values[i] = new(interface{})
 		}
-		err := rows.Scan(values...)
-		mValue := dest.(*map[string]interface{})
-		valuesToMap(*mValue, values, columns)
+	}
+	err := scanColumns(rows, values...)
+	if *dest == nil {
+		*dest = make(map[string]interface{}, len(columns))
+	}
+	valuesToMap(*dest, values, columns)
 
-		return err
-	default:
-		//scan to struct
-		destValue := reflect.ValueOf(dest).Elem() //destType.Elem()
-		initStructValues(destValue, columns, values)
+	return err
+}
+
+// kindFamily returns the family of the kind, the conversions are allowed within the same family only
+func kindFamily(kind reflect.Kind) reflect.Kind {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return reflect.Int
+	case reflect.Float32, reflect.Float64:
+		return reflect.Float64
+	}
+	return kind
+}
+
+// convertKey converts the key to the map key type, e.g. int64 to int and []byte to string,
+// the conversions changing the value like int to string and float to int are not allowed
+func convertKey(key reflect.Value, keyType reflect.Type) (reflect.Value, bool) {
+	if !key.IsValid() {
+		return key, false
+	}
+	if key.Type().AssignableTo(keyType) {
+		return key, true
+	}
+	if b, ok := key.Interface().([]byte); ok && keyType.Kind() == reflect.String {
+		return reflect.ValueOf(string(b)).Convert(keyType), true
+	}
+	if kindFamily(key.Kind()) != kindFamily(keyType.Kind()) || !key.Type().ConvertibleTo(keyType) {
+		return key, false
+	}
+	return key.Convert(keyType), true
+}
+
+func (s *scanner) scanMap(rows *sql.Rows, dest interface{}, keyColumn string) error {
+
+	defer rows.Close()
+
+	destType := reflect.TypeOf(dest)
+	if destType == nil || destType.Kind() != reflect.Ptr || destType.Elem().Kind() != reflect.Map {
+		return fmt.Errorf("ploto: ScanMap dest must be a pointer to map, got %v", destType)
+	}
 
-		err := rows.Scan(values...)
+	columns, err := rows.Columns()
+	if err != nil {
 		return err
+	}
+	keyIdx := -1
+	for i, column := range columns {
+		if column == keyColumn {
+			keyIdx = i
+			break
+		}
+	}
+	if keyIdx == -1 {
+		return fmt.Errorf("ploto: ScanMap key column %s not found", keyColumn)
+	}
+
+	mapVal := reflect.ValueOf(dest).Elem()
+	if mapVal.IsNil() {
+		mapVal.Set(reflect.MakeMap(mapVal.Type()))
+	}
+	keyType := mapVal.Type().Key()
+
+	// map[K]V, map[K]*V, map[K][]V, map[K][]*V
+	valueType := mapVal.Type().Elem()
+	isSlice := valueType.Kind() == reflect.Slice && valueType.Elem().Kind() != reflect.Uint8
+	itemType := valueType
+	if isSlice {
+		itemType = valueType.Elem()
+	}
+	isPtr := itemType.Kind() == reflect.Ptr
+	if isPtr {
+		itemType = itemType.Elem()
+	}
+
+	var plan *scanPlan
+	var columnTypes []*sql.ColumnType
+	isStruct := itemType != mapType && !s.isScalar(itemType)
+	if isStruct {
+		if plan, err = s.newScanPlan(itemType, columns); err != nil {
+			return err
+		}
+	} else if itemType == mapType {
+		if columnTypes, err = rows.ColumnTypes(); err != nil {
+			return err
+		}
+	} else if len(columns) != 2 {
+		return fmt.Errorf("ploto: ScanMap into %s requires the key column and one value column", valueType)
+	}
+
+	for row := 0; rows.Next(); row++ {
+		item := reflect.New(itemType)
+		keyHolder := reflect.New(keyType)
+		var key reflect.Value
+		var ok bool
 
+		switch {
+		case isStruct:
+			values := make([]interface{}, len(columns))
+			initStructValues(item.Elem(), plan, values)
+			f := plan.fields[keyIdx]
+			if f == nil {
+				values[keyIdx] = keyHolder.Interface()
+			}
+			if err := s.scanStructValues(rows, item.Elem(), plan, values, row); err != nil {
+				return err
+			}
+
+			key = keyHolder.Elem()
+			if f != nil {
+				key = fieldByIndexNoAlloc(item.Elem(), f.index)
+			}
+		case itemType == mapType:
+			m := make(map[string]interface{}, len(columns))
+			err := s.scanMapRow(rows, &m, columns, columnTypes)
+			item.Elem().Set(reflect.ValueOf(m))
+			if err != nil {
+				return newScanError(err, mapType, nil, row)
+			}
+			if v := item.Elem().MapIndex(reflect.ValueOf(keyColumn)); v.IsValid() {
+				key = reflect.ValueOf(v.Interface())
+			}
+		default:
+			values := make([]interface{}, 2)
+			values[keyIdx] = keyHolder.Interface()
+			values[1-keyIdx] = item.Interface()
+			if converter, ok := lookupFieldConverter(s.converters, itemType); ok {
+				values[1-keyIdx] = &fieldScanner{converter: converter, dest: item.Interface()}
+			}
+			if err := scanColumns(rows, values...); err != nil {
+				return newScanError(err, valueType, nil, row)
+			}
+			key = keyHolder.Elem()
+		}
+
+		if key, ok = convertKey(key, keyType); !ok {
+			return fmt.Errorf("ploto: ScanMap key column %s can not be converted to %s", keyColumn, keyType)
+		}
+
+		value := item.Elem()
+		if isPtr {
+			value = item
+		}
+		if isSlice {
+			values := mapVal.MapIndex(key)
+			if !values.IsValid() {
+				values = reflect.Zero(valueType)
+			}
+			value = reflect.Append(values, value)
+		}
+		mapVal.SetMapIndex(key, value)
 	}
+
+	return rows.Err()
 }
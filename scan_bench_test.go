@@ -285,3 +285,39 @@ func BenchmarkScanRows2(b *testing.B) {
 
 	}
 }
+
+func BenchmarkScanScalars(b *testing.B) {
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		b.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		dataRows := sqlmock.NewRows([]string{"id"})
+		for id := 1; id <= 100; id++ {
+			dataRows.AddRow(id)
+		}
+		mock.ExpectQuery("SELECT id FROM users WHERE id<?").WithArgs(100).WillReturnRows(dataRows)
+
+		rows, err := db.Query("SELECT id FROM users WHERE id<?", 100)
+		if err != nil {
+			b.Fatalf("sql.Query: Error: %+v\n", err)
+		}
+
+		b.StartTimer()
+		var ids []int64
+		err = ScanSlice(rows, &ids)
+		if err != nil || len(ids) != 100 {
+			b.Fatalf("ScanSlice error %+v %d", err, len(ids))
+		}
+
+		rows.Close()
+
+	}
+}
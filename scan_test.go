@@ -1,6 +1,8 @@
 package ploto
 
 import (
+	"database/sql"
+	"reflect"
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
@@ -186,3 +188,240 @@ func TestScanSlices2(t *testing.T) {
 	t.Logf("userex result:%+v", user[0])
 
 }
+
+func TestColumnFieldsCache(t *testing.T) {
+	typ := reflect.TypeOf(UsersEx{})
+	columns := []string{"id", "name", "unknown"}
+
+	fields := columnFields(typ, DefaultNameMapper, columns)
+	if len(fields) != 3 {
+		t.Fatalf("columnFields length error %d", len(fields))
+	}
+	if !reflect.DeepEqual(fields[0].index, []int{0, 0}) || fields[2] != nil {
+		t.Fatalf("columnFields index error %+v", fields)
+	}
+
+	cached := columnFields(typ, DefaultNameMapper, []string{"id", "name", "unknown"})
+	if &cached[0] != &fields[0] {
+		t.Fatalf("columnFields should be cached")
+	}
+}
+
+func TestScanNestedStruct(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	type Article struct {
+		Id     int64  `db:"id"`
+		Title  string `db:"title"`
+		Author Users  `db:"author"`
+		Editor *Users `db:"editor"`
+	}
+
+	dataRows := sqlmock.NewRows([]string{"id", "title", "author.id", "author_name", "editor.id", "editor.name"}).
+		AddRow(1, "title1", 10, "author10", 20, "editor20").
+		AddRow(2, "title2", 11, "author11", nil, nil)
+	mock.ExpectQuery("SELECT (.+) FROM articles").WillReturnRows(dataRows)
+
+	rows, err := db.Query("SELECT * FROM articles")
+	if err != nil {
+		t.Fatalf("error '%s' was not expected while retrieving mock rows", err)
+	}
+
+	var articles []Article
+	err = ScanResult(rows, &articles)
+	if err != nil {
+		t.Fatalf("scan nested struct error %+v", err)
+	}
+
+	if len(articles) != 2 || articles[0].Author.Id != 10 || articles[0].Author.Name != "author10" {
+		t.Fatalf("scan nested struct error %+v", articles)
+	}
+	if articles[0].Editor == nil || articles[0].Editor.Name != "editor20" {
+		t.Fatalf("scan nested pointer struct error %+v", articles[0].Editor)
+	}
+	if articles[1].Editor != nil {
+		t.Fatalf("nested pointer struct should be nil %+v", articles[1].Editor)
+	}
+}
+
+func TestScanEmbeddedPointerStruct(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	type Timestamps struct {
+		CreatedTime string `db:"created_time"`
+		UpdatedTime string `db:"updated_time"`
+	}
+
+	type Posts struct {
+		*Timestamps
+		Id int64 `db:"id"`
+	}
+
+	dataRows := sqlmock.NewRows([]string{"id", "created_time", "updated_time"}).
+		AddRow(1, "2021-10-01 00:00:00", "2021-10-02 00:00:00").
+		AddRow(2, "2021-10-03 00:00:00", "2021-10-04 00:00:00")
+	mock.ExpectQuery("SELECT (.+) FROM posts").WillReturnRows(dataRows)
+
+	rows, err := db.Query("SELECT * FROM posts")
+	if err != nil {
+		t.Fatalf("error '%s' was not expected while retrieving mock rows", err)
+	}
+
+	var posts []*Posts
+	err = ScanResult(rows, &posts)
+	if err != nil {
+		t.Fatalf("scan embedded pointer struct error %+v", err)
+	}
+
+	if len(posts) != 2 || posts[0].Timestamps == nil || posts[1].UpdatedTime != "2021-10-04 00:00:00" {
+		t.Fatalf("scan embedded pointer struct error %+v", posts)
+	}
+	if posts[0].Timestamps == posts[1].Timestamps {
+		t.Fatalf("embedded pointer struct should be allocated for every row")
+	}
+}
+
+func TestScanScalarSlices(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+
+	db := &DB{DB: mockDB}
+
+	mock.ExpectQuery("SELECT id FROM users").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2).AddRow(3))
+	var ids []int64
+	if err := db.Query("SELECT id FROM users").Scan(&ids); err != nil || len(ids) != 3 || ids[2] != 3 {
+		t.Fatalf("scan []int64 error %+v %+v", err, ids)
+	}
+
+	mock.ExpectQuery("SELECT name FROM users").WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("a").AddRow(nil))
+	var names []sql.NullString
+	if err := db.Query("SELECT name FROM users").Scan(&names); err != nil || len(names) != 2 || names[1].Valid {
+		t.Fatalf("scan []sql.NullString error %+v %+v", err, names)
+	}
+
+	mock.ExpectQuery("SELECT name FROM users").WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("a").AddRow("b"))
+	var ptrNames []*string
+	if err := db.Query("SELECT name FROM users").Scan(&ptrNames); err != nil || len(ptrNames) != 2 || *ptrNames[1] != "b" {
+		t.Fatalf("scan []*string error %+v %+v", err, ptrNames)
+	}
+
+	mock.ExpectQuery("SELECT data FROM users").WillReturnRows(sqlmock.NewRows([]string{"data"}).AddRow([]byte("abc")))
+	var data [][]byte
+	if err := db.Query("SELECT data FROM users").Scan(&data); err != nil || len(data) != 1 || string(data[0]) != "abc" {
+		t.Fatalf("scan [][]byte error %+v %+v", err, data)
+	}
+
+	mock.ExpectQuery("SELECT (.+) FROM users").WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "a").AddRow(2, "b"))
+	var maps []map[string]interface{}
+	if err := db.Query("SELECT id,name FROM users").Scan(&maps); err != nil || len(maps) != 2 || maps[1]["name"] != "b" {
+		t.Fatalf("scan []map[string]interface{} error %+v %+v", err, maps)
+	}
+}
+
+func TestScanError(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+
+	db := &DB{DB: mockDB}
+
+	dataRows := sqlmock.NewRows([]string{"id", "name", "created_time", "updated_time"}).
+		AddRow(1, "1111", "2021-10-01 00:00:00", "2021-10-01 00:00:00").
+		AddRow(nil, "2222", "2021-10-01 00:00:00", "2021-10-01 00:00:00")
+	mock.ExpectQuery("SELECT (.+) FROM users").WillReturnRows(dataRows)
+
+	var users []UsersEx
+	err = db.Query("SELECT id,name,created_time,updated_time FROM users").Scan(&users)
+
+	scanErr, ok := err.(*ScanError)
+	if !ok {
+		t.Fatalf("should return ScanError: %+v", err)
+	}
+	if scanErr.Column != "id" || scanErr.ColumnIndex != 0 || scanErr.Field != "Users.Id" || scanErr.Row != 1 ||
+		scanErr.Type != reflect.TypeOf(UsersEx{}) || scanErr.Err == nil {
+		t.Fatalf("ScanError error %+v", scanErr)
+	}
+	t.Logf("scan error: %s", err)
+
+	mock.ExpectQuery("SELECT id FROM users").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow("x"))
+	var ids []int64
+	err = db.Query("SELECT id FROM users").Scan(&ids)
+	if scanErr, ok := err.(*ScanError); !ok || scanErr.Row != 1 || scanErr.Field != "" || scanErr.Type != reflect.TypeOf(int64(0)) {
+		t.Fatalf("should return ScanError for scalar: %+v", err)
+	}
+
+	type ScoreUsers struct {
+		Id    int64  `db:"id"`
+		Name  string `db:"name"`
+		Score int    `db:"score"`
+	}
+	mock.ExpectQuery("SELECT (.+) FROM users").WillReturnRows(sqlmock.NewRows([]string{"id", "name", "score"}).AddRow(1, "a", "x"))
+	var user ScoreUsers
+	err = db.QueryRow("SELECT id,name,score FROM users").Scan(&user)
+	if scanErr, ok := err.(*ScanError); !ok || scanErr.Column != "score" || scanErr.ColumnIndex != 2 || scanErr.Field != "Score" || scanErr.Err == nil {
+		t.Fatalf("should return ScanError for the failed column: %+v", err)
+	}
+}
+
+func TestScanNullZero(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+
+	type NullUsers struct {
+		Id    int64  `db:"id"`
+		Name  string `db:"name,nullzero"`
+		Score int    `db:"score"`
+	}
+
+	db := &DB{DB: mockDB}
+
+	mock.ExpectQuery("SELECT (.+) FROM users").WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, nil))
+	var user NullUsers
+	err = db.QueryRow("SELECT id,name FROM users").Scan(&user)
+	if err != nil || user.Id != 1 || user.Name != "" {
+		t.Fatalf("nullzero tag error %+v %+v", err, user)
+	}
+
+	mock.ExpectQuery("SELECT (.+) FROM users").WillReturnRows(sqlmock.NewRows([]string{"id", "name", "score"}).AddRow(1, "a", nil))
+	err = db.QueryRow("SELECT id,name,score FROM users").Scan(&user)
+	if _, ok := err.(*ScanError); !ok {
+		t.Fatalf("NULL without nullzero should return ScanError %+v", err)
+	}
+
+	db.NullZero = true
+	mock.ExpectQuery("SELECT (.+) FROM users").WillReturnRows(sqlmock.NewRows([]string{"id", "name", "score"}).AddRow(2, "b", nil).AddRow(3, nil, 5))
+	var users []NullUsers
+	err = db.Query("SELECT id,name,score FROM users").Scan(&users)
+	if err != nil || len(users) != 2 || users[0].Score != 0 || users[1].Name != "" || users[1].Score != 5 {
+		t.Fatalf("DB NullZero error %+v %+v", err, users)
+	}
+
+	mock.ExpectQuery("SELECT max").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(nil))
+	maxID := int64(10)
+	err = db.QueryRow("SELECT max(id) FROM users").Scan(&maxID)
+	if err != nil || maxID != 0 {
+		t.Fatalf("DB NullZero scalar error %+v %d", err, maxID)
+	}
+
+	mock.ExpectQuery("SELECT (.+) FROM users").WillReturnRows(sqlmock.NewRows([]string{"id", "name", "score"}).AddRow(1, nil, 1))
+	err = db.QueryRow("SELECT id,name,score FROM users").Strict().Scan(&user)
+	if _, ok := err.(*ScanError); !ok {
+		t.Fatalf("NULL in strict mode should return ScanError %+v", err)
+	}
+}
@@ -3,8 +3,6 @@ package ploto
 import (
 	"context"
 	"database/sql"
-
-	"github.com/feiin/sqlstring"
 )
 
 type Tx struct {
@@ -34,19 +32,27 @@ func (tx *Tx) Exec(query string, args ...interface{}) (sql.Result, error) {
 // ExecContext executes a query that doesn't return rows.
 // For example: an INSERT and UPDATE.
 func (tx *Tx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	query, args, err := tx.DB.bindArgs(query, args)
+	if err != nil {
+		return nil, err
+	}
 	if tx.DB.LogSql {
-		tx.DB.logger.Info(ctx, "Executing (%s):%s", tx.TransactionID, sqlstring.Format(query, args...))
+		tx.DB.logger.Info(ctx, "Executing (%s):%s", tx.TransactionID, formatQuery(tx.DB.Dialect, query, args...))
 	}
 	return tx.Tx.ExecContext(ctx, query, args...)
 }
 
 // QueryContext executes a query that returns rows, typically a SELECT.
 func (tx *Tx) QueryContext(ctx context.Context, query string, args ...interface{}) *RowsResult {
+	query, args, err := tx.DB.bindArgs(query, args)
+	if err != nil {
+		return &RowsResult{LastError: err, scanner: tx.DB.newScanner(ctx)}
+	}
 	if tx.DB.LogSql {
-		tx.DB.logger.Info(ctx, "Query (%s):%s", tx.TransactionID, sqlstring.Format(query, args...))
+		tx.DB.logger.Info(ctx, "Query (%s):%s", tx.TransactionID, formatQuery(tx.DB.Dialect, query, args...))
 	}
 	rs, err := tx.Tx.QueryContext(ctx, query, args...)
-	return &RowsResult{rs, err}
+	return &RowsResult{Rows: rs, LastError: err, scanner: tx.DB.newScanner(ctx)}
 }
 
 // Query executes a query that returns rows, typically a SELECT.
@@ -70,12 +76,58 @@ func (tx *Tx) QueryRow(query string, args ...interface{}) *RowResult {
 // Otherwise, the *Row's Scan scans the first selected row and discards
 // the rest.
 func (tx *Tx) QueryRowContext(ctx context.Context, query string, args ...interface{}) *RowResult {
+	query, args, err := tx.DB.bindArgs(query, args)
+	if err != nil {
+		return &RowResult{LastError: err, scanner: tx.DB.newScanner(ctx)}
+	}
 	if tx.DB.LogSql {
-		tx.DB.logger.Info(ctx, "Query (%s):%s", tx.TransactionID, sqlstring.Format(query, args...))
+		tx.DB.logger.Info(ctx, "Query (%s):%s", tx.TransactionID, formatQuery(tx.DB.Dialect, query, args...))
 	}
 	rows, err := tx.Tx.QueryContext(ctx, query, args...)
 
-	return &RowResult{rows: rows, LastError: err}
+	return &RowResult{rows: rows, LastError: err, scanner: tx.DB.newScanner(ctx)}
+}
+
+// NamedExec executes a query with the named parameters :name or @name that doesn't return rows.
+func (tx *Tx) NamedExec(query string, arg interface{}) (sql.Result, error) {
+	return tx.NamedExecContext(context.Background(), query, arg)
+}
+
+// NamedExecContext executes a query with the named parameters :name or @name that doesn't return rows.
+func (tx *Tx) NamedExecContext(ctx context.Context, query string, arg interface{}) (sql.Result, error) {
+	query, args, err := bindNamed(tx.DB.Dialect, query, arg, tx.DB.NameMapper)
+	if err != nil {
+		return nil, err
+	}
+	return tx.ExecContext(ctx, query, args...)
+}
+
+// NamedQuery executes a query with the named parameters that returns rows, typically a SELECT.
+func (tx *Tx) NamedQuery(query string, arg interface{}) *RowsResult {
+	return tx.NamedQueryContext(context.Background(), query, arg)
+}
+
+// NamedQueryContext executes a query with the named parameters that returns rows, typically a SELECT.
+func (tx *Tx) NamedQueryContext(ctx context.Context, query string, arg interface{}) *RowsResult {
+	query, args, err := bindNamed(tx.DB.Dialect, query, arg, tx.DB.NameMapper)
+	if err != nil {
+		return &RowsResult{LastError: err, scanner: tx.DB.newScanner(ctx)}
+	}
+	return tx.QueryContext(ctx, query, args...)
+}
+
+// NamedQueryRow executes a query with the named parameters that is expected to return at most one row.
+func (tx *Tx) NamedQueryRow(query string, arg interface{}) *RowResult {
+	return tx.NamedQueryRowContext(context.Background(), query, arg)
+}
+
+// NamedQueryRowContext executes a query with the named parameters that is expected to return at most one row.
+func (tx *Tx) NamedQueryRowContext(ctx context.Context, query string, arg interface{}) *RowResult {
+	query, args, err := bindNamed(tx.DB.Dialect, query, arg, tx.DB.NameMapper)
+	if err != nil {
+		return &RowResult{LastError: err, scanner: tx.DB.newScanner(ctx)}
+	}
+	return tx.QueryRowContext(ctx, query, args...)
 }
 
 // Rollback aborts the transaction.
@@ -0,0 +1,285 @@
+package ploto
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Snapshot records the column values of the scanned model. Embed it into the model to make Update
+// write only the columns changed since the model was scanned or updated.
+type Snapshot struct {
+	values map[string]interface{}
+}
+
+// snapshotter is implemented by the models embedding Snapshot
+type snapshotter interface {
+	snapshot() *Snapshot
+}
+
+// snapshot returns the Snapshot itself
+func (s *Snapshot) snapshot() *Snapshot {
+	return s
+}
+
+var snapshotterType = reflect.TypeOf((*snapshotter)(nil)).Elem()
+
+// ErrStaleObject returned by Update when the version of the model is outdated, the row is updated or deleted by others
+var ErrStaleObject = errors.New("ploto: stale object")
+
+// changed reports whether the column value differs from the recorded one, true if not recorded
+func (s *Snapshot) changed(column string, value interface{}) bool {
+	recorded, ok := s.values[column]
+	return !ok || !reflect.DeepEqual(recorded, value)
+}
+
+// takeSnapshot records the column values of the item if it embeds Snapshot
+func takeSnapshot(item reflect.Value, converters *ConverterRegistry) {
+	hook, ok := item.Addr().Interface().(snapshotter)
+	if !ok {
+		return
+	}
+
+	info := getStructInfo(item.Type())
+	values := make(map[string]interface{}, len(info.columns))
+	for _, f := range info.columns {
+		if value, err := snapshotValue(converters, item, f); err == nil {
+			values[f.name] = value
+		}
+	}
+	hook.snapshot().values = values
+}
+
+// snapshotValue returns the encoded column value to be compared, which shares no memory with the item.
+// The value is encoded by the json tag, the registered converter or driver.Valuer, the pointers are
+// dereferenced and the other slices and maps are copied
+func snapshotValue(converters *ConverterRegistry, item reflect.Value, f *structField) (interface{}, error) {
+	arg, err := fieldArg(item, f)
+	if err != nil || arg == nil {
+		return arg, err
+	}
+
+	value := reflect.ValueOf(arg)
+	if value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return nil, nil
+		}
+		value = value.Elem()
+	}
+
+	if converter, ok := lookupConverter(converters, value.Type()); ok {
+		if arg, err = converter.ToDB(value.Interface()); err != nil {
+			return nil, err
+		}
+		value = reflect.ValueOf(arg)
+	} else if valuer, ok := value.Interface().(driver.Valuer); ok {
+		if arg, err = valuer.Value(); err != nil {
+			return nil, err
+		}
+		value = reflect.ValueOf(arg)
+	}
+	if !value.IsValid() {
+		return nil, nil
+	}
+	return copyValue(value).Interface(), nil
+}
+
+// copyValue returns the deep copy of the slices and maps
+func copyValue(value reflect.Value) reflect.Value {
+	switch value.Kind() {
+	case reflect.Slice:
+		if value.IsNil() {
+			return value
+		}
+		copied := reflect.MakeSlice(value.Type(), value.Len(), value.Len())
+		for i := 0; i < value.Len(); i++ {
+			copied.Index(i).Set(copyValue(value.Index(i)))
+		}
+		return copied
+	case reflect.Map:
+		if value.IsNil() {
+			return value
+		}
+		copied := reflect.MakeMapWithSize(value.Type(), value.Len())
+		iter := value.MapRange()
+		for iter.Next() {
+			copied.SetMapIndex(iter.Key(), copyValue(iter.Value()))
+		}
+		return copied
+	}
+	return value
+}
+
+// getSnapshot returns the recorded Snapshot of the item, nil if not embedded or not recorded
+func getSnapshot(item reflect.Value) *Snapshot {
+	hook, ok := item.Addr().Interface().(snapshotter)
+	if !ok || hook.snapshot().values == nil {
+		return nil
+	}
+	return hook.snapshot()
+}
+
+// Update updates the model, a pointer to struct, in the table. The columns are the db tagged fields except
+// the pk and autoincr fields, the omitempty fields with the zero value are skipped. If the model embeds Snapshot,
+// only the columns changed since it was scanned are written, no statement is executed if nothing changed.
+// where is the condition with ? placeholders followed by the args, e.g. "id=?", 1, the pk fields are matched if empty.
+// The version field, db:"version,version", is matched and incremented, ErrStaleObject is returned if no row is updated.
+func (db *DB) Update(ctx context.Context, table string, model interface{}, where ...interface{}) (sql.Result, error) {
+	return update(ctx, db, table, model, where)
+}
+
+// Update updates the model in the transaction, see DB.Update
+func (tx *Tx) Update(ctx context.Context, table string, model interface{}, where ...interface{}) (sql.Result, error) {
+	return update(ctx, tx, table, model, where)
+}
+
+// update updates the model on the session
+func update(ctx context.Context, sess session, table string, model interface{}, where []interface{}) (sql.Result, error) {
+	item, err := modelValue(model, "Update")
+	if err != nil {
+		return nil, err
+	}
+
+	dialect := sess.client().Dialect
+	snapshot := getSnapshot(item)
+
+	var b strings.Builder
+	b.WriteString("UPDATE ")
+	b.WriteString(quoteIdentifier(dialect, table))
+	b.WriteString(" SET ")
+
+	var args []interface{}
+	var keys []*structField
+	var version *structField
+	for _, f := range writeFields(sess.client().Converters, item.Type()) {
+		if f.pk || f.autoIncr {
+			keys = append(keys, f)
+			continue
+		}
+		if f.version {
+			version = f
+			continue
+		}
+		value := fieldByIndexNoAlloc(item, f.index)
+		if f.omitEmpty && (!value.IsValid() || value.IsZero()) {
+			continue
+		}
+		if snapshot != nil {
+			if value, err := snapshotValue(sess.client().Converters, item, f); err == nil && !snapshot.changed(f.name, value) {
+				continue
+			}
+		}
+
+		arg, err := fieldArg(item, f)
+		if err != nil {
+			return nil, err
+		}
+		if len(args) > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(quoteIdentifier(dialect, f.name))
+		b.WriteString("=?")
+		args = append(args, arg)
+	}
+	if len(args) == 0 {
+		return driver.RowsAffected(0), nil
+	}
+
+	var current, next reflect.Value
+	if version != nil {
+		current = fieldByIndexNoAlloc(item, version.index)
+		if next, err = nextVersion(current); err != nil {
+			return nil, err
+		}
+		b.WriteByte(',')
+		b.WriteString(quoteIdentifier(dialect, version.name))
+		b.WriteString("=?")
+		args = append(args, next.Interface())
+	}
+
+	b.WriteString(" WHERE ")
+	whereArgs, err := writeWhere(&b, dialect, item, keys, where, version != nil)
+	if err != nil {
+		return nil, err
+	}
+	args = append(args, whereArgs...)
+
+	if version != nil {
+		b.WriteString(" AND ")
+		b.WriteString(quoteIdentifier(dialect, version.name))
+		b.WriteString("=?")
+		args = append(args, current.Interface())
+	}
+
+	result, err := sess.ExecContext(ctx, Rebind(dialect, b.String()), args...)
+	if err != nil {
+		return result, err
+	}
+
+	if version != nil {
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return result, err
+		}
+		if affected == 0 {
+			return result, ErrStaleObject
+		}
+		current.Set(next)
+	}
+	if snapshot != nil {
+		takeSnapshot(item, sess.client().Converters)
+	}
+	return result, nil
+}
+
+// nextVersion returns the incremented value of the version field
+func nextVersion(current reflect.Value) (reflect.Value, error) {
+	next := reflect.New(current.Type()).Elem()
+	switch current.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		next.SetInt(current.Int() + 1)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		next.SetUint(current.Uint() + 1)
+	default:
+		return next, fmt.Errorf("ploto: version field must be an integer, got %s", current.Type())
+	}
+	return next, nil
+}
+
+// writeWhere writes the where condition with ? placeholders and returns the args,
+// the condition of the key fields if where is empty, the condition is parenthesized if paren
+func writeWhere(b *strings.Builder, dialect string, item reflect.Value, keys []*structField, where []interface{}, paren bool) ([]interface{}, error) {
+	if len(where) > 0 {
+		cond, ok := where[0].(string)
+		if !ok || cond == "" {
+			return nil, fmt.Errorf("ploto: where condition must be a string, got %T", where[0])
+		}
+		if paren {
+			cond = "(" + cond + ")"
+		}
+		b.WriteString(cond)
+		return where[1:], nil
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("ploto: %s has no pk field for the where condition", item.Type())
+	}
+	args := make([]interface{}, 0, len(keys))
+	for i, f := range keys {
+		if i > 0 {
+			b.WriteString(" AND ")
+		}
+		b.WriteString(quoteIdentifier(dialect, f.name))
+		b.WriteString("=?")
+		arg, err := fieldArg(item, f)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+	return args, nil
+}
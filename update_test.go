@@ -0,0 +1,193 @@
+package ploto
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+type UpdateUsers struct {
+	Snapshot
+	Id    int64    `db:"id,pk,autoincr"`
+	Name  string   `db:"name"`
+	Email *string  `db:"email"`
+	Tags  []string `db:"tags,json"`
+}
+
+func TestUpdate(t *testing.T) {
+	mockDB, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	db := &DB{DB: mockDB}
+	ctx := context.Background()
+
+	type Users struct {
+		Id   int64  `db:"id,pk"`
+		Name string `db:"name"`
+		Age  int    `db:"age,omitempty"`
+	}
+	mock.ExpectExec("UPDATE `users` SET `name`=? WHERE `id`=?").WithArgs("ploto", 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	if _, err := db.Update(ctx, "users", &Users{Id: 1, Name: "ploto"}); err != nil {
+		t.Fatalf("Update error %+v", err)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `users` SET `name`=?,`age`=? WHERE name=? and id in (?,?)").WithArgs("ploto", 18, "feiin", 1, 2).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Begin error %+v", err)
+	}
+	if _, err := tx.Update(ctx, "users", &Users{Name: "ploto", Age: 18}, "name=? and id in (?)", "feiin", []int{1, 2}); err != nil {
+		t.Fatalf("Tx Update error %+v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit error %+v", err)
+	}
+
+	type Logs struct {
+		Content string `db:"content"`
+	}
+	if _, err := db.Update(ctx, "logs", &Logs{Content: "x"}); err == nil {
+		t.Fatalf("Update without pk and where should be error")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestUpdateSnapshot(t *testing.T) {
+	mockDB, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	db := &DB{DB: mockDB, Dialect: "sqlserver"}
+	ctx := context.Background()
+
+	mock.ExpectQuery("select * from users where id=@p1").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "email", "tags"}).AddRow(1, "ploto", "ploto@test.com", `["a"]`))
+	var user UpdateUsers
+	if err := db.QueryRow("select * from users where id=@p1", 1).Scan(&user); err != nil {
+		t.Fatalf("QueryRow error %+v", err)
+	}
+
+	// nothing changed
+	if result, err := db.Update(ctx, "users", &user); err != nil {
+		t.Fatalf("Update unchanged error %+v", err)
+	} else if affected, _ := result.RowsAffected(); affected != 0 {
+		t.Fatalf("Update unchanged affected %d", affected)
+	}
+
+	*user.Email = "feiin@test.com"
+	user.Tags = append(user.Tags, "b")
+	mock.ExpectExec("UPDATE [users] SET [email]=@p1,[tags]=@p2 WHERE [id]=@p3").WithArgs("feiin@test.com", `["a","b"]`, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	if _, err := db.Update(ctx, "users", &user); err != nil {
+		t.Fatalf("Update changed error %+v", err)
+	}
+
+	user.Name = "feiin"
+	mock.ExpectExec("UPDATE [users] SET [name]=@p1 WHERE [id]=@p2").WithArgs("feiin", 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	if _, err := db.Update(ctx, "users", &user); err != nil {
+		t.Fatalf("Update after update error %+v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestUpdateVersion(t *testing.T) {
+	mockDB, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	db := &DB{DB: mockDB}
+	ctx := context.Background()
+
+	type Accounts struct {
+		Id      int64 `db:"id,pk"`
+		Balance int64 `db:"balance"`
+		Version int   `db:"version,version"`
+	}
+
+	account := Accounts{Id: 1, Balance: 100, Version: 3}
+	mock.ExpectExec("UPDATE `accounts` SET `balance`=?,`version`=? WHERE `id`=? AND `version`=?").WithArgs(100, 4, 1, 3).WillReturnResult(sqlmock.NewResult(0, 1))
+	if _, err := db.Update(ctx, "accounts", &account); err != nil {
+		t.Fatalf("Update version error %+v", err)
+	}
+	if account.Version != 4 {
+		t.Fatalf("Update version %d", account.Version)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `accounts` SET `balance`=?,`version`=? WHERE (id=? or id=?) AND `version`=?").WithArgs(50, 5, 1, 2, 4).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Begin error %+v", err)
+	}
+	account.Balance = 50
+	if _, err := tx.Update(ctx, "accounts", &account, "id=? or id=?", 1, 2); err != ErrStaleObject {
+		t.Fatalf("Update stale version error %+v", err)
+	}
+	if account.Version != 4 {
+		t.Fatalf("Update stale version %d", account.Version)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatalf("Rollback error %+v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestUpdateSnapshotInPlace(t *testing.T) {
+	mockDB, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+
+	converters := NewConverterRegistry()
+	converters.Register(tagSet{}, tagSetConverter)
+	db := &DB{DB: mockDB, Converters: converters}
+	ctx := context.Background()
+
+	type Posts struct {
+		Snapshot
+		Id   int64  `db:"id,pk"`
+		Tags tagSet `db:"tags"`
+		Data []byte `db:"data"`
+	}
+
+	mock.ExpectQuery("select * from posts").WillReturnRows(sqlmock.NewRows([]string{"id", "tags", "data"}).AddRow(1, "a,b", []byte("x")))
+	var post Posts
+	if err := db.QueryRow("select * from posts").Scan(&post); err != nil {
+		t.Fatalf("QueryRow error %+v", err)
+	}
+
+	// changed in place, sharing the backing arrays with the scanned values
+	post.Tags[0] = "z"
+	post.Data[0] = 'y'
+	mock.ExpectExec("UPDATE `posts` SET `tags`=?,`data`=? WHERE `id`=?").WithArgs("z,b", []byte("y"), 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	if _, err := db.Update(ctx, "posts", &post); err != nil {
+		t.Fatalf("Update in place error %+v", err)
+	}
+
+	post.Tags[1] = "c"
+	mock.ExpectExec("UPDATE `posts` SET `tags`=? WHERE `id`=?").WithArgs("z,c", 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	if _, err := db.Update(ctx, "posts", &post); err != nil {
+		t.Fatalf("Update in place after update error %+v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,251 @@
+package ploto
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+)
+
+// Upsert inserts the model, a pointer to struct, or updates the existing row which conflicts with it,
+// by INSERT ... ON DUPLICATE KEY UPDATE on mysql and MERGE on sqlserver. The columns are written like Insert.
+// conflictColumns are the unique key columns matched by MERGE, mysql uses the unique keys of the table instead.
+// updateColumns are the columns updated on conflict, all the written columns except conflictColumns if empty.
+// The id of the inserted or updated row is set to the autoincr field.
+func (db *DB) Upsert(ctx context.Context, table string, model interface{}, conflictColumns []string, updateColumns []string) (sql.Result, error) {
+	return upsert(ctx, db, table, model, conflictColumns, updateColumns)
+}
+
+// Upsert inserts or updates the model in the transaction, see DB.Upsert
+func (tx *Tx) Upsert(ctx context.Context, table string, model interface{}, conflictColumns []string, updateColumns []string) (sql.Result, error) {
+	return upsert(ctx, tx, table, model, conflictColumns, updateColumns)
+}
+
+// BulkUpsert inserts or updates the rows, a slice of structs or struct pointers, in batches like BulkInsert,
+// see DB.Upsert for conflictColumns and updateColumns. omitempty is ignored and the autoincr fields are not set.
+func (db *DB) BulkUpsert(ctx context.Context, table string, rows interface{}, conflictColumns []string, updateColumns []string, opts *BulkOptions) (sql.Result, error) {
+	return bulkUpsert(ctx, db, table, rows, conflictColumns, updateColumns, opts)
+}
+
+// BulkUpsert inserts or updates the rows in the transaction, see DB.BulkUpsert
+func (tx *Tx) BulkUpsert(ctx context.Context, table string, rows interface{}, conflictColumns []string, updateColumns []string, opts *BulkOptions) (sql.Result, error) {
+	return bulkUpsert(ctx, tx, table, rows, conflictColumns, updateColumns, opts)
+}
+
+// upsertStatement the upsert statement of the dialect without the rows
+type upsertStatement struct {
+	dialect  string
+	table    string
+	columns  []string
+	conflict []string
+	update   []string
+	// autoIncr the autoincr column returned by the statement
+	autoIncr string
+}
+
+// newUpsertStatement validates the conflict and update columns against the written columns
+func newUpsertStatement(dialect string, table string, columns []string, conflictColumns []string, updateColumns []string) (*upsertStatement, error) {
+	written := make(map[string]bool, len(columns))
+	for _, column := range columns {
+		written[column] = true
+	}
+
+	if isSQLServer(dialect) && len(conflictColumns) == 0 {
+		return nil, fmt.Errorf("ploto: upsert on %s requires the conflict columns", dialect)
+	}
+	conflict := make(map[string]bool, len(conflictColumns))
+	for _, column := range conflictColumns {
+		if !written[column] {
+			return nil, fmt.Errorf("ploto: upsert conflict column %s is not written", column)
+		}
+		conflict[column] = true
+	}
+
+	for _, column := range updateColumns {
+		if !written[column] {
+			return nil, fmt.Errorf("ploto: upsert update column %s is not written", column)
+		}
+	}
+	if len(updateColumns) == 0 {
+		for _, column := range columns {
+			if !conflict[column] {
+				updateColumns = append(updateColumns, column)
+			}
+		}
+	}
+
+	return &upsertStatement{dialect: dialect, table: table, columns: columns, conflict: conflictColumns, update: updateColumns}, nil
+}
+
+// build builds the statement of the rows and returns the args
+func (stmt *upsertStatement) build(rows [][]interface{}) (string, []interface{}) {
+	var b strings.Builder
+	b.WriteString(stmt.prefix())
+	args := writeRows(&b, placeholderKind(stmt.dialect), rows)
+	b.WriteString(stmt.suffix())
+	return b.String(), args
+}
+
+// prefix returns the statement before the rows
+func (stmt *upsertStatement) prefix() string {
+	var b strings.Builder
+	if isSQLServer(stmt.dialect) {
+		b.WriteString("MERGE INTO ")
+		b.WriteString(quoteIdentifier(stmt.dialect, stmt.table))
+		b.WriteString(" WITH (HOLDLOCK) AS target USING (VALUES ")
+		return b.String()
+	}
+
+	b.WriteString("INSERT INTO ")
+	b.WriteString(quoteIdentifier(stmt.dialect, stmt.table))
+	b.WriteString(" (")
+	writeColumns(&b, stmt.dialect, stmt.columns)
+	b.WriteString(") VALUES ")
+	return b.String()
+}
+
+// suffix returns the statement after the rows
+func (stmt *upsertStatement) suffix() string {
+	var b strings.Builder
+	quote := func(column string) string {
+		return quoteIdentifier(stmt.dialect, column)
+	}
+
+	if !isSQLServer(stmt.dialect) {
+		b.WriteString(" ON DUPLICATE KEY UPDATE ")
+		sets := make([]string, 0, len(stmt.update)+1)
+		for _, column := range stmt.update {
+			sets = append(sets, quote(column)+"=VALUES("+quote(column)+")")
+		}
+		if stmt.autoIncr != "" {
+			// LastInsertId returns the id of the updated row too
+			sets = append(sets, quote(stmt.autoIncr)+"=LAST_INSERT_ID("+quote(stmt.autoIncr)+")")
+		}
+		if len(sets) == 0 {
+			sets = append(sets, quote(stmt.columns[0])+"="+quote(stmt.columns[0]))
+		}
+		b.WriteString(strings.Join(sets, ","))
+		return b.String()
+	}
+
+	b.WriteString(") AS source (")
+	writeColumns(&b, stmt.dialect, stmt.columns)
+	b.WriteString(") ON ")
+	for i, column := range stmt.conflict {
+		if i > 0 {
+			b.WriteString(" AND ")
+		}
+		b.WriteString("target." + quote(column) + "=source." + quote(column))
+	}
+
+	update := stmt.update
+	if len(update) == 0 && stmt.autoIncr != "" {
+		// the matched row must be updated to be returned by OUTPUT
+		update = stmt.conflict[:1]
+	}
+	if len(update) > 0 {
+		b.WriteString(" WHEN MATCHED THEN UPDATE SET ")
+		for i, column := range update {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString("target." + quote(column) + "=source." + quote(column))
+		}
+	}
+
+	b.WriteString(" WHEN NOT MATCHED THEN INSERT (")
+	writeColumns(&b, stmt.dialect, stmt.columns)
+	b.WriteString(") VALUES (")
+	for i, column := range stmt.columns {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString("source." + quote(column))
+	}
+	b.WriteByte(')')
+
+	if stmt.autoIncr != "" {
+		b.WriteString(" OUTPUT INSERTED.")
+		b.WriteString(quote(stmt.autoIncr))
+	}
+	b.WriteByte(';')
+	return b.String()
+}
+
+// upsert inserts or updates the model on the session
+func upsert(ctx context.Context, sess session, table string, model interface{}, conflictColumns []string, updateColumns []string) (sql.Result, error) {
+	item, err := modelValue(model, "Upsert")
+	if err != nil {
+		return nil, err
+	}
+	if err := callBeforeInsert(ctx, item); err != nil {
+		return nil, err
+	}
+
+	dialect := sess.client().Dialect
+	columns, args, autoIncr, err := insertValues(sess.client().Converters, item)
+	if err != nil {
+		return nil, err
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("ploto: Upsert %s has no columns", item.Type())
+	}
+
+	stmt, err := newUpsertStatement(dialect, table, columns, conflictColumns, updateColumns)
+	if err != nil {
+		return nil, err
+	}
+	if autoIncr != nil {
+		stmt.autoIncr = autoIncr.name
+	}
+	query, args := stmt.build([][]interface{}{args})
+
+	if autoIncr != nil && isSQLServer(dialect) {
+		id := fieldByIndexAlloc(item, autoIncr.index)
+		if err := sess.QueryRowContext(ctx, query, args...).Scan(id.Addr().Interface()); err != nil {
+			return nil, err
+		}
+		return driver.RowsAffected(1), nil
+	}
+
+	result, err := sess.ExecContext(ctx, query, args...)
+	if err != nil || autoIncr == nil {
+		return result, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return result, err
+	}
+	return result, setInsertID(fieldByIndexAlloc(item, autoIncr.index), id)
+}
+
+// bulkUpsert inserts or updates the rows on the session
+func bulkUpsert(ctx context.Context, sess session, table string, rows interface{}, conflictColumns []string, updateColumns []string, opts *BulkOptions) (sql.Result, error) {
+	columns, values, err := bulkValues(ctx, sess.client().Converters, rows, "BulkUpsert", false)
+	if err != nil {
+		return nil, err
+	}
+
+	stmt, err := newUpsertStatement(sess.client().Dialect, table, columns, conflictColumns, updateColumns)
+	if err != nil {
+		return nil, err
+	}
+
+	var affected int64
+	headerSize := len(stmt.prefix()) + len(stmt.suffix())
+	for _, batch := range splitBatches(stmt.dialect, opts, headerSize, len(columns), values) {
+		query, args := stmt.build(batch)
+		result, err := sess.ExecContext(ctx, query, args...)
+		if err != nil {
+			return driver.RowsAffected(affected), err
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return driver.RowsAffected(affected), err
+		}
+		affected += n
+	}
+	return driver.RowsAffected(affected), nil
+}
@@ -0,0 +1,102 @@
+package ploto
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+type UpsertUsers struct {
+	Id    int64  `db:"id,pk,autoincr"`
+	Email string `db:"email"`
+	Name  string `db:"name"`
+	Age   int    `db:"age,omitempty"`
+}
+
+func TestUpsert(t *testing.T) {
+	mockDB, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	db := &DB{DB: mockDB}
+	ctx := context.Background()
+
+	mock.ExpectExec("INSERT INTO `users` (`email`,`name`) VALUES (?,?) ON DUPLICATE KEY UPDATE `name`=VALUES(`name`),`id`=LAST_INSERT_ID(`id`)").
+		WithArgs("a@test.com", "a").WillReturnResult(sqlmock.NewResult(3, 2))
+	user := UpsertUsers{Email: "a@test.com", Name: "a"}
+	if _, err := db.Upsert(ctx, "users", &user, []string{"email"}, nil); err != nil {
+		t.Fatalf("Upsert error %+v", err)
+	}
+	if user.Id != 3 {
+		t.Fatalf("Upsert user %+v", user)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `users` (`email`,`name`,`age`) VALUES (?,?,?),(?,?,?) ON DUPLICATE KEY UPDATE `age`=VALUES(`age`)").
+		WithArgs("a@test.com", "a", 18, "b@test.com", "b", 0).WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Begin error %+v", err)
+	}
+	users := []UpsertUsers{{Email: "a@test.com", Name: "a", Age: 18}, {Email: "b@test.com", Name: "b"}}
+	if _, err := tx.BulkUpsert(ctx, "users", users, []string{"email"}, []string{"age"}, nil); err != nil {
+		t.Fatalf("BulkUpsert error %+v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit error %+v", err)
+	}
+
+	if _, err := db.Upsert(ctx, "users", &user, []string{"email"}, []string{"age"}); err == nil {
+		t.Fatalf("Upsert omitted update column should be error")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestUpsertSQLServer(t *testing.T) {
+	mockDB, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	db := &DB{DB: mockDB, Dialect: "sqlserver"}
+	ctx := context.Background()
+
+	mock.ExpectQuery("MERGE INTO [users] WITH (HOLDLOCK) AS target USING (VALUES (@p1,@p2)) AS source ([email],[name]) ON target.[email]=source.[email]"+
+		" WHEN MATCHED THEN UPDATE SET target.[name]=source.[name] WHEN NOT MATCHED THEN INSERT ([email],[name]) VALUES (source.[email],source.[name]) OUTPUT INSERTED.[id];").
+		WithArgs("a@test.com", "a").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+	user := UpsertUsers{Email: "a@test.com", Name: "a"}
+	if _, err := db.Upsert(ctx, "users", &user, []string{"email"}, nil); err != nil {
+		t.Fatalf("Upsert error %+v", err)
+	}
+	if user.Id != 5 {
+		t.Fatalf("Upsert user %+v", user)
+	}
+
+	type Logs struct {
+		Day   time.Time `db:"day"`
+		Count int       `db:"count"`
+	}
+	day := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectExec("MERGE INTO [logs] WITH (HOLDLOCK) AS target USING (VALUES (@p1,@p2),(@p3,@p4)) AS source ([day],[count]) ON target.[day]=source.[day]"+
+		" WHEN MATCHED THEN UPDATE SET target.[count]=source.[count] WHEN NOT MATCHED THEN INSERT ([day],[count]) VALUES (source.[day],source.[count]);").
+		WithArgs(day, 1, day.AddDate(0, 0, 1), 2).WillReturnResult(sqlmock.NewResult(0, 2))
+	logs := []*Logs{{Day: day, Count: 1}, {Day: day.AddDate(0, 0, 1), Count: 2}}
+	if _, err := db.BulkUpsert(ctx, "logs", logs, []string{"day"}, nil, nil); err != nil {
+		t.Fatalf("BulkUpsert error %+v", err)
+	}
+
+	if _, err := db.BulkUpsert(ctx, "logs", logs, nil, nil, nil); err == nil {
+		t.Fatalf("BulkUpsert without conflict columns should be error")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}